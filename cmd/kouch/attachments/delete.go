@@ -0,0 +1,55 @@
+package attachments
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"delete"}, deleteAttCmd)
+}
+
+func deleteAttCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "attachment [target]",
+		Aliases: []string{"att"},
+		Short:   "Deletes an attachment.",
+		Long: "Deletes the attachment from the specified document, and outputs the document's new revision.\n\n" +
+			kouch.TargetHelpText(kouch.TargetAttachment),
+		RunE: deleteAttachmentCmd,
+	}
+	addCommonFlags(cmd.Flags())
+	cmd.Flags().BoolP(kouch.FlagAutoRev, kouch.FlagShortAutoRev, false, "Fetch the current rev before deletion. Use with caution!")
+	cmd.Flags().Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
+	return cmd
+}
+
+func deleteAttachmentCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := deleteAttachmentOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	return kouch.ConflictError(util.ChttpDo(ctx, http.MethodDelete, util.AttPath(o), o))
+}
+
+func deleteAttachmentOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetAttachment, flags)
+	if err != nil {
+		return nil, err
+	}
+	o.Options.FullCommit, err = flags.GetBool(kouch.FlagFullCommit)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
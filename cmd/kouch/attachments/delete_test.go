@@ -0,0 +1,133 @@
+package attachments
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestDeleteAttachmentOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     *kouch.Config
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}{
+		{
+			name: "rev",
+			args: []string{"--" + kouch.FlagRev, "xyz", "foo.txt"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Filename: "foo.txt"},
+				Options: &chttp.Options{
+					Query: url.Values{"rev": []string{"xyz"}},
+				},
+			},
+		},
+		{
+			name: "full commit",
+			args: []string{"--" + kouch.FlagFullCommit, "foo.txt"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Filename: "foo.txt"},
+				Options: &chttp.Options{FullCommit: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.conf == nil {
+				test.conf = &kouch.Config{}
+			}
+			cmd := deleteAttCmd()
+			if e := cmd.ParseFlags(test.args); e != nil {
+				t.Fatal(e)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, test.conf)
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(kouch.SetConf(ctx, test.conf), cmd)
+			opts, err := deleteAttachmentOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestDeleteAttachmentCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No filename provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true,"id":"bar","rev":"3-967a00dff5e02add41819138abb3284d"}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "DELETE", s.URL+"/foo/bar/baz.txt?rev=2-xyz", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz.txt", "--rev", "2-xyz", "-F", "yaml"},
+			Stdout: "id: bar\nok: true\nrev: 3-967a00dff5e02add41819138abb3284d",
+		}
+	})
+	tests.Add("auto rev", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Content-Type", "application/json")
+			if r.Method == http.MethodHead {
+				if r.URL.Path != "/foo/bar" {
+					t.Errorf("Unexpected HEAD path: %s", r.URL.Path)
+				}
+				w.Header().Add("ETag", `"2-xyz"`)
+				w.WriteHeader(200)
+				return
+			}
+			if rev := r.URL.Query().Get("rev"); rev != "2-xyz" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+			w.WriteHeader(200)
+			_, _ = w.Write([]byte(`{"ok":true,"id":"bar","rev":"3-967a00dff5e02add41819138abb3284d"}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz.txt", "-F", "yaml", "--auto-rev"},
+			Stdout: "id: bar\nok: true\nrev: 3-967a00dff5e02add41819138abb3284d",
+		}
+	})
+	tests.Add("conflict", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 409,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error":"conflict","reason":"Document update conflict."}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar/baz.txt", "--rev", "1-xyz"},
+			Err:    "Document update conflict: the revision is missing or not current. Provide the current revision with --rev, or use --auto-rev.",
+			Status: kouch.ExitConflict,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"delete", "att"}))
+}
@@ -0,0 +1,250 @@
+package bulk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Bulk docs specific flags
+const (
+	flagBatchSize   = "batch-size"
+	flagConcurrency = "concurrency"
+	flagNewEdits    = "new-edits"
+)
+
+const defaultBatchSize = 1000
+
+func init() {
+	registry.Register([]string{"post"}, postBulkDocsCmd)
+}
+
+func postBulkDocsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bulk-docs [target]",
+		Short: "Uploads documents in bulk.",
+		Long: "Creates, updates or deletes documents in bulk.\n\n" +
+			"Documents are read from a JSON array, a stream of JSON objects (one per line), or a YAML multi-document stream, and uploaded in batches as they are read. " +
+			"If the input is invalid, batches read before the error may already have been uploaded. " +
+			"A summary is output once all batches have been uploaded. If the server rejected any document, kouch exits with status 102.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: postBulkDocsCmdRun,
+	}
+	f := cmd.Flags()
+	f.Int(flagBatchSize, defaultBatchSize, "The number of documents to upload per request.")
+	f.Int(flagConcurrency, 1, "The number of batches to upload concurrently.")
+	f.Bool(flagNewEdits, true, "Assign new revisions to the documents. Use --"+flagNewEdits+"=false to store the documents with their existing revisions, as replication does.")
+	return cmd
+}
+
+func postBulkDocsCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateDatabase(o.Target); e != nil {
+		return e
+	}
+	b, err := newBulkDocs(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	in, format, err := bulkInput(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	summary, err := b.upload(ctx, o, newDocReader(in, format))
+	if err != nil {
+		return err
+	}
+	if e := util.OutputValue(ctx, summary.output()); e != nil {
+		return e
+	}
+	if summary.failed > 0 {
+		return errors.NewExitError(kouch.ExitBulkErrors, "%d of %d documents failed", summary.failed, summary.total)
+	}
+	return nil
+}
+
+// bulkInput returns the input, and the format forced by --data-json or
+// --data-yaml, if any. The context's input holds only the first value given to
+// those flags, so the full input is opened again.
+func bulkInput(ctx context.Context, cmd *cobra.Command) (io.ReadCloser, string, error) {
+	jsonData, _ := cmd.Flags().GetString(kouch.FlagDataJSON)
+	yamlData, _ := cmd.Flags().GetString(kouch.FlagDataYAML)
+	if jsonData == "" && yamlData == "" {
+		return kouch.Input(ctx), "", nil
+	}
+	_ = kouch.Input(ctx).Close()
+	flag, in, err := kio.OpenInput(cmd)
+	return in, flag, err
+}
+
+type bulkDocs struct {
+	batchSize   int
+	concurrency int
+	newEdits    bool
+}
+
+func newBulkDocs(flags *pflag.FlagSet) (*bulkDocs, error) {
+	b := &bulkDocs{}
+	var err error
+	if b.batchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if b.concurrency, err = flags.GetInt(flagConcurrency); err != nil {
+		return nil, err
+	}
+	if b.newEdits, err = flags.GetBool(flagNewEdits); err != nil {
+		return nil, err
+	}
+	if b.batchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagBatchSize)
+	}
+	if b.concurrency < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagConcurrency)
+	}
+	return b, nil
+}
+
+// bulkResult is the server's response for a single document.
+type bulkResult struct {
+	ID     string `json:"id"`
+	Rev    string `json:"rev,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// bulkSummary counts the uploaded documents. Successes are not counted from
+// the results, as the server omits them when new_edits is false.
+type bulkSummary struct {
+	total, failed int
+	errors        []bulkResult
+}
+
+func (s *bulkSummary) add(results []bulkResult) {
+	for _, result := range results {
+		if result.Error != "" {
+			s.failed++
+			s.errors = append(s.errors, result)
+		}
+	}
+}
+
+func (s *bulkSummary) output() map[string]interface{} {
+	out := map[string]interface{}{
+		"ok":     s.total - s.failed,
+		"failed": s.failed,
+	}
+	if len(s.errors) > 0 {
+		errs := make([]interface{}, len(s.errors))
+		for i, e := range s.errors {
+			errs[i] = map[string]interface{}{"id": e.ID, "error": e.Error, "reason": e.Reason}
+		}
+		out["errors"] = errs
+	}
+	return out
+}
+
+// upload reads docs and uploads them in batches, as each batch fills, with up
+// to b.concurrency requests in flight. The first read or request error aborts
+// the remaining batches.
+func (b *bulkDocs) upload(ctx context.Context, o *kouch.Options, docs *docReader) (*bulkSummary, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	path := util.DatabasePath(o) + "/_bulk_docs"
+
+	summary := &bulkSummary{}
+	var (
+		results  [][]bulkResult
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	sem := make(chan struct{}, b.concurrency)
+	send := func(batch []interface{}) {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			return
+		}
+		mu.Lock()
+		i := len(results)
+		results = append(results, nil)
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			body := map[string]interface{}{"docs": batch}
+			if !b.newEdits {
+				body["new_edits"] = false
+			}
+			opts := *o.Options
+			opts.Body = chttp.EncodeBody(body)
+			var res []bulkResult
+			if _, err := c.DoJSON(ctx, http.MethodPost, path, &opts, &res); err != nil {
+				fail(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = res
+		}()
+	}
+
+	batch := make([]interface{}, 0, b.batchSize)
+	for ctx.Err() == nil {
+		doc, err := docs.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+		summary.total++
+		if batch = append(batch, doc); len(batch) == b.batchSize {
+			send(batch)
+			batch = make([]interface{}, 0, b.batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		send(batch)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if summary.total == 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No documents provided")
+	}
+	for _, res := range results {
+		summary.add(res)
+	}
+	return summary, nil
+}
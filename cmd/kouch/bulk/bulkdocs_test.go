@@ -0,0 +1,149 @@
+package bulk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/post"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+// bulkDocsServer responds to each _bulk_docs request with a success for every
+// document, except those with the ID "bad". It records the requests received.
+func bulkDocsServer(t *testing.T, requests *[]map[string]interface{}) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/foo/_bulk_docs" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		*requests = append(*requests, req)
+		mu.Unlock()
+		docs, _ := req["docs"].([]interface{})
+		results := make([]map[string]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			id, _ := doc.(map[string]interface{})["_id"].(string)
+			if id == "bad" {
+				results = append(results, map[string]interface{}{"id": id, "error": "forbidden", "reason": "bad doc"})
+				continue
+			}
+			results = append(results, map[string]interface{}{"id": id, "ok": true, "rev": "1-x"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+}
+
+func TestPostBulkDocsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"-d", "[]"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid batch size", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "--" + flagBatchSize, "0", "-d", "[]"},
+		Err:    "--batch-size must be positive",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("batches", func(t *testing.T) interface{} {
+		var requests []map[string]interface{}
+		s := bulkDocsServer(t, &requests)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			if len(requests) != 2 {
+				t.Errorf("Expected 2 requests, got %d", len(requests))
+			}
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagBatchSize, "2", "-d", "{\"_id\":\"a\"}\n{\"_id\":\"b\"}\n{\"_id\":\"c\"}\n", "-F", "yaml"},
+			Stdout: "failed: 0\nok: 3",
+		}
+	})
+	tests.Add("no documents", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", "[]"},
+		Err:    "No documents provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("yaml file", func(t *testing.T) interface{} {
+		dir, err := ioutil.TempDir("", "kouch-bulk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests.Cleanup(func() { _ = os.RemoveAll(dir) })
+		filename := filepath.Join(dir, "docs.yaml")
+		if e := ioutil.WriteFile(filename, []byte("_id: a\n---\n_id: b\n---\n_id: c\n"), 0644); e != nil {
+			t.Fatal(e)
+		}
+		var requests []map[string]interface{}
+		s := bulkDocsServer(t, &requests)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			if len(requests) != 2 {
+				t.Errorf("Expected 2 requests, got %d", len(requests))
+			}
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagBatchSize, "2", "--" + kouch.FlagDataYAML, "@" + filename},
+			Stdout: `{"failed":0,"ok":3}`,
+		}
+	})
+	tests.Add("ndjson data", func(t *testing.T) interface{} {
+		var requests []map[string]interface{}
+		s := bulkDocsServer(t, &requests)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagDataJSON, "{\"_id\":\"a\"}\n{\"_id\":\"bad\"}\n"},
+			Stdout: `{"errors":[{"error":"forbidden","id":"bad","reason":"bad doc"}],"failed":1,"ok":1}`,
+			Err:    "1 of 2 documents failed",
+			Status: kouch.ExitBulkErrors,
+		}
+	})
+	tests.Add("failures", func(t *testing.T) interface{} {
+		var requests []map[string]interface{}
+		s := bulkDocsServer(t, &requests)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagConcurrency, "2", "--" + flagBatchSize, "1", "-d", `[{"_id":"a"},{"_id":"bad"}]`},
+			Stdout: `{"errors":[{"error":"forbidden","id":"bad","reason":"bad doc"}],"failed":1,"ok":1}`,
+			Err:    "1 of 2 documents failed",
+			Status: kouch.ExitBulkErrors,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"post", "bulk-docs"}))
+}
+
+func TestPostBulkDocsNewEdits(t *testing.T) {
+	var requests []map[string]interface{}
+	s := bulkDocsServer(t, &requests)
+	defer s.Close()
+	test.ValidateCmdTest([]string{"post", "bulk-docs"})(t, test.CmdTest{
+		Args:   []string{s.URL + "/foo", "--" + flagNewEdits + "=false", "--data-yaml", "_id: a\n_rev: 1-x"},
+		Stdout: `{"failed":0,"ok":1}`,
+	})
+	expected := []map[string]interface{}{
+		{"docs": []interface{}{map[string]interface{}{"_id": "a", "_rev": "1-x"}}, "new_edits": false},
+	}
+	if d := diff.Interface(expected, requests); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,231 @@
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Bulk get specific flags
+const (
+	flagIDsFile     = "ids-file"
+	flagRevs        = "revs"
+	flagAttachments = "attachments"
+	flagLatest      = "latest"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getBulkCmd)
+}
+
+func getBulkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bulk [target] [id ...]",
+		Short: "Fetches multiple documents.",
+		Long: "Fetches multiple documents in a single request, with _bulk_get. On servers which do not support _bulk_get, _all_docs is used instead, and --" + flagRevs + " and --" + flagLatest + " are ignored.\n\n" +
+			"Document IDs are read from the arguments following the target, from the file named by --" + flagIDsFile + ", or otherwise from the input, one per line.\n\n" +
+			"With --" + kouch.FlagOutputFormat + "=raw, the documents are output one per line. With other output formats, they are output as a single array. " +
+			"Documents which could not be fetched are output as an object with the id, error and reason.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		Annotations: map[string]string{kouch.AnnotationExtraArgs: ""},
+		RunE:        getBulkCmdRun,
+	}
+	f := cmd.Flags()
+	f.String(flagIDsFile, "", "A file containing the document IDs to fetch, one per line. Use '-' for stdin.")
+	f.Bool(flagRevs, false, "Include the revision history of each document.")
+	f.Bool(flagAttachments, false, "Include the content of attachments.")
+	f.Bool(flagLatest, false, "Fetch the latest leaf revisions.")
+	return cmd
+}
+
+func getBulkCmdRun(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getBulkOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateDatabase(o.Target); e != nil {
+		return e
+	}
+	var ids []string
+	if len(args) > 1 {
+		ids = args[1:]
+	} else {
+		ids, err = readIDs(ctx, cmd.Flags())
+		if err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No document IDs provided")
+	}
+	docs, err := getBulk(ctx, o, ids)
+	if err != nil {
+		return err
+	}
+	return outputDocs(ctx, docs)
+}
+
+func getBulkOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range []string{flagRevs, flagAttachments, flagLatest} {
+		if e := o.SetParamBool(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	return o, nil
+}
+
+// readIDs reads document IDs, one per line, from the file named by
+// --ids-file, or from the input.
+func readIDs(ctx context.Context, flags *pflag.FlagSet) ([]string, error) {
+	filename, err := flags.GetString(flagIDsFile)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader
+	switch filename {
+	case "", "-":
+		r = kouch.Input(ctx)
+	default:
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, errors.WrapExitError(chttp.ExitReadError, err)
+		}
+		defer f.Close() // nolint: errcheck
+		r = f
+	}
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, errors.WrapExitError(chttp.ExitReadError, scanner.Err())
+}
+
+type bulkGetResponse struct {
+	Results []struct {
+		Docs []struct {
+			OK    json.RawMessage `json:"ok"`
+			Error json.RawMessage `json:"error"`
+		} `json:"docs"`
+	} `json:"results"`
+}
+
+type allDocsResponse struct {
+	Rows []struct {
+		Key   string          `json:"key"`
+		Error string          `json:"error"`
+		Doc   json.RawMessage `json:"doc"`
+		Value struct {
+			Deleted bool `json:"deleted"`
+		} `json:"value"`
+	} `json:"rows"`
+}
+
+// getBulk fetches the documents with _bulk_get, falling back to _all_docs if
+// the server does not support _bulk_get.
+func getBulk(ctx context.Context, o *kouch.Options, ids []string) ([]json.RawMessage, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		reqs[i] = map[string]string{"id": id}
+	}
+	opts := *o.Options
+	opts.Body = chttp.EncodeBody(map[string]interface{}{"docs": reqs})
+	var res bulkGetResponse
+	_, err = c.DoJSON(ctx, http.MethodPost, util.DatabasePath(o)+"/_bulk_get", &opts, &res)
+	switch kivik.StatusCode(err) {
+	case kivik.StatusNotFound, kivik.StatusMethodNotAllowed:
+		return getAllDocs(ctx, c, o, ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var docs []json.RawMessage
+	for _, result := range res.Results {
+		for _, doc := range result.Docs {
+			if doc.OK != nil {
+				docs = append(docs, doc.OK)
+				continue
+			}
+			docs = append(docs, doc.Error)
+		}
+	}
+	return docs, nil
+}
+
+func getAllDocs(ctx context.Context, c *chttp.Client, o *kouch.Options, ids []string) ([]json.RawMessage, error) {
+	opts := *o.Options
+	opts.Query = url.Values{"include_docs": {"true"}}
+	if attachments := o.Options.Query.Get(flagAttachments); attachments != "" {
+		opts.Query.Set(flagAttachments, attachments)
+	}
+	opts.Body = chttp.EncodeBody(map[string]interface{}{"keys": ids})
+	var res allDocsResponse
+	if _, err := c.DoJSON(ctx, http.MethodPost, util.DatabasePath(o)+"/_all_docs", &opts, &res); err != nil {
+		return nil, err
+	}
+	docs := make([]json.RawMessage, 0, len(res.Rows))
+	for _, row := range res.Rows {
+		switch {
+		case row.Error != "":
+			docs = append(docs, missingDoc(row.Key, row.Error, "missing"))
+		case row.Value.Deleted:
+			docs = append(docs, missingDoc(row.Key, "not_found", "deleted"))
+		default:
+			docs = append(docs, row.Doc)
+		}
+	}
+	return docs, nil
+}
+
+func missingDoc(id, err, reason string) json.RawMessage {
+	doc, _ := json.Marshal(map[string]string{"id": id, "error": err, "reason": reason})
+	return doc
+}
+
+// outputDocs writes docs as a single array with the selected output format,
+// or one per line with the raw output format.
+func outputDocs(ctx context.Context, docs []json.RawMessage) error {
+	if _, ok := kouch.Output(ctx).(kio.ValueWriter); !ok {
+		w := kio.Underlying(kouch.Output(ctx))
+		if err := util.WriteRows(w, docs); err != nil {
+			return err
+		}
+		if c, ok := w.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	}
+	array := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		if err := json.Unmarshal(doc, &array[i]); err != nil {
+			return errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+	}
+	return util.OutputValue(ctx, array)
+}
@@ -0,0 +1,132 @@
+package bulk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetBulkCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no ids", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", "\n"},
+		Err:    "No document IDs provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("bulk get", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(strings.NewReader(`{"results":[` +
+				`{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-a"}}]},` +
+				`{"id":"b","docs":[{"error":{"id":"b","rev":"undefined","error":"not_found","reason":"missing"}}]}` +
+				`]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/foo/_bulk_get" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			if revs := r.URL.Query().Get("revs"); revs != "true" {
+				t.Errorf("Unexpected revs: %s", revs)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"docs":[{"id":"a"},{"id":"b"}]}`), body); d != nil {
+				t.Error(d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "a", "b", "--" + flagRevs, "-F", "raw"},
+			Stdout: `{"_id":"a","_rev":"1-a"}` + "\n" +
+				`{"id":"b","rev":"undefined","error":"not_found","reason":"missing"}` + "\n",
+		}
+	})
+	tests.Add("ids from input", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"results":[{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-a"}}]}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"docs":[{"id":"a"}]}`), body); d != nil {
+				t.Error(d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", "a\n\n"},
+			Stdout: `[{"_id":"a","_rev":"1-a"}]`,
+		}
+	})
+	tests.Add("ids file", func(t *testing.T) interface{} {
+		dir, err := ioutil.TempDir("", "kouch-bulk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests.Cleanup(func() { _ = os.RemoveAll(dir) })
+		filename := filepath.Join(dir, "ids")
+		if e := ioutil.WriteFile(filename, []byte("a\n"), 0644); e != nil {
+			t.Fatal(e)
+		}
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"results":[{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-a"}}]}]}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagIDsFile, filename, "-F", "yaml"},
+			Stdout: "- _id: a\n  _rev: 1-a",
+		}
+	})
+	tests.Add("fallback", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/foo/_bulk_get" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				_, _ = w.Write([]byte(`{"error":"method_not_allowed","reason":"Only GET,HEAD,DELETE allowed"}`))
+				return
+			}
+			if r.URL.Path != "/foo/_all_docs" || r.URL.Query().Get("include_docs") != "true" {
+				t.Errorf("Unexpected request: %s", r.URL)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"keys":["a","b","c"]}`), body); d != nil {
+				t.Error(d)
+			}
+			_, _ = w.Write([]byte(`{"total_rows":2,"rows":[` +
+				`{"id":"a","key":"a","value":{"rev":"1-a"},"doc":{"_id":"a","_rev":"1-a"}},` +
+				`{"id":"b","key":"b","value":{"rev":"2-b","deleted":true},"doc":null},` +
+				`{"key":"c","error":"not_found"}` +
+				`]}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "a", "b", "c", "-F", "raw"},
+			Stdout: `{"_id":"a","_rev":"1-a"}` + "\n" +
+				`{"error":"not_found","id":"b","reason":"deleted"}` + "\n" +
+				`{"error":"not_found","id":"c","reason":"missing"}` + "\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "bulk"}))
+}
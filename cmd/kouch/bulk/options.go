@@ -0,0 +1,130 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"unicode"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/icza/dyno"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func validateDatabase(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
+
+// docReader reads documents one at a time from a JSON array, a stream of JSON
+// objects (such as NDJSON), or a YAML multi-document stream.
+type docReader struct {
+	next func() (interface{}, error)
+}
+
+// newDocReader returns a docReader for r. format may be kouch.FlagDataJSON or
+// kouch.FlagDataYAML to force the input format, or "" to detect it from the
+// first character of the input.
+func newDocReader(r io.Reader, format string) *docReader {
+	br := bufio.NewReader(r)
+	first := peekNonSpace(br)
+	d := &docReader{}
+	switch {
+	case first == '[' && format != kouch.FlagDataYAML:
+		d.next = jsonArrayDocs(json.NewDecoder(br))
+	case format == kouch.FlagDataJSON, first == '{' && format == "":
+		dec := json.NewDecoder(br)
+		d.next = func() (interface{}, error) {
+			var doc json.RawMessage
+			err := dec.Decode(&doc)
+			return doc, err
+		}
+	default:
+		d.next = yamlDocs(yaml.NewDecoder(br))
+	}
+	return d
+}
+
+// peekNonSpace discards leading whitespace from r, and returns the next byte
+// without consuming it, or 0 if there is none.
+func peekNonSpace(r *bufio.Reader) byte {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0
+		}
+		if !unicode.IsSpace(rune(c)) {
+			_ = r.UnreadByte()
+			return c
+		}
+	}
+}
+
+// jsonArrayDocs returns the elements of a JSON array, one at a time.
+func jsonArrayDocs(dec *json.Decoder) func() (interface{}, error) {
+	var started, done bool
+	return func() (interface{}, error) {
+		if done {
+			return nil, io.EOF
+		}
+		if !started {
+			started = true
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+		if dec.More() {
+			var doc json.RawMessage
+			err := dec.Decode(&doc)
+			return doc, err
+		}
+		done = true
+		if _, err := dec.Token(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// yamlDocs returns the documents in a YAML stream. Top-level sequences are
+// flattened, and empty documents are skipped.
+func yamlDocs(dec *yaml.Decoder) func() (interface{}, error) {
+	var pending []interface{}
+	return func() (interface{}, error) {
+		for len(pending) == 0 {
+			var doc interface{}
+			if err := dec.Decode(&doc); err != nil {
+				return nil, err
+			}
+			switch t := dyno.ConvertMapI2MapS(doc).(type) {
+			case nil:
+			case []interface{}:
+				pending = t
+			default:
+				return t, nil
+			}
+		}
+		doc := pending[0]
+		pending = pending[1:]
+		return doc, nil
+	}
+}
+
+// Next returns the next document, or io.EOF when there are no more.
+func (d *docReader) Next() (interface{}, error) {
+	doc, err := d.next()
+	if err != nil && err != io.EOF {
+		return nil, errors.WrapExitError(chttp.ExitPostError, err)
+	}
+	return doc, err
+}
@@ -0,0 +1,93 @@
+package bulk
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+)
+
+func TestDocReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   string
+		expected string
+		err      string
+		status   int
+	}{
+		{
+			name:     "empty",
+			input:    " \n",
+			expected: `null`,
+		},
+		{
+			name:     "json array",
+			input:    `[{"_id":"a"},{"_id":"b"}]`,
+			expected: `[{"_id":"a"},{"_id":"b"}]`,
+		},
+		{
+			name:     "ndjson",
+			input:    "{\"_id\":\"a\"}\n{\"_id\":\"b\"}\n",
+			expected: `[{"_id":"a"},{"_id":"b"}]`,
+		},
+		{
+			name:     "yaml stream",
+			input:    "_id: a\nfoo: [1, 2]\n---\n_id: b\n",
+			expected: `[{"_id":"a","foo":[1,2]},{"_id":"b"}]`,
+		},
+		{
+			name:     "yaml sequence",
+			input:    "- _id: a\n- _id: b\n",
+			expected: `[{"_id":"a"},{"_id":"b"}]`,
+		},
+		{
+			name:   "invalid json",
+			input:  `[{"_id":"a"}`,
+			err:    "unexpected end of JSON input",
+			status: chttp.ExitPostError,
+		},
+		{
+			name:     "forced json",
+			input:    "\"a\"\n\"b\"\n",
+			format:   kouch.FlagDataJSON,
+			expected: `["a","b"]`,
+		},
+		{
+			name:     "forced yaml flow mapping",
+			input:    "{_id: a}\n---\n{_id: b}\n",
+			format:   kouch.FlagDataYAML,
+			expected: `[{"_id":"a"},{"_id":"b"}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newDocReader(strings.NewReader(test.input), test.format)
+			var docs []interface{}
+			var err error
+			for {
+				var doc interface{}
+				if doc, err = r.Next(); err != nil {
+					break
+				}
+				docs = append(docs, doc)
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			testy.ExitStatusError(t, test.err, test.status, err)
+			result, err := json.Marshal(docs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(test.expected), result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
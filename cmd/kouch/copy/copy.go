@@ -0,0 +1,18 @@
+package copy
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, copyCmd)
+}
+
+func copyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy",
+		Short: "Copy a resource.",
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// All-dbs specific flags
+const (
+	flagInfo = "info"
+)
+
+func init() {
+	registry.Register([]string{"get"}, allDbsCmd)
+}
+
+func allDbsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "all-dbs [target]",
+		Aliases: []string{"alldbs", "dbs"},
+		Short:   "Lists the databases on the server.",
+		Long: "Lists the databases on the server. With --" + flagInfo + ", the metadata for each database is fetched as well.\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+		RunE: getAllDbsCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagStartKey, "", "Return databases starting with the specified name.")
+	f.String(kouch.FlagEndKey, "", "Stop returning databases when the specified name is reached.")
+	f.Int(kouch.FlagLimit, 0, "Limit the number of databases returned. 0 means no limit.")
+	f.Int(kouch.FlagSkip, 0, "Skip this number of databases before returning results.")
+	f.Bool(kouch.FlagDescending, false, "Return the databases in descending order.")
+	f.Bool(flagInfo, false, "Fetch the metadata for each database, from /_dbs_info.")
+	f.String(kouch.FlagKeys, "", "With --"+flagInfo+", fetch metadata only for the named databases. Provide a JSON array, or a comma-separated list.")
+	return cmd
+}
+
+func getAllDbsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := allDbsOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	info, err := cmd.Flags().GetBool(flagInfo)
+	if err != nil {
+		return err
+	}
+	if !info {
+		if o.Options.Body != nil {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s requires --%s", kouch.FlagKeys, flagInfo)
+		}
+		return util.ChttpDo(ctx, http.MethodGet, "/_all_dbs", o)
+	}
+	if o.Options.Body != nil {
+		return util.ChttpDo(ctx, http.MethodPost, "/_dbs_info", o)
+	}
+	return util.ChttpDo(ctx, http.MethodGet, "/_dbs_info", o)
+}
+
+func allDbsOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetRoot, flags)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range []string{kouch.FlagStartKey, kouch.FlagEndKey} {
+		if e := o.SetParamJSONString(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{kouch.FlagLimit, kouch.FlagSkip} {
+		if e := o.SetParamInt(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	if e := o.SetParamBool(flags, kouch.FlagDescending); e != nil {
+		return nil, e
+	}
+	return o, o.SetKeys(flags, kouch.JSONString)
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestAllDbsOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}{
+		{
+			name: "root from target",
+			args: []string{"http://foo.com/"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Root: "http://foo.com/"},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "start and end keys",
+			args: []string{"--" + kouch.FlagStartKey, "bar", "--" + kouch.FlagEndKey, "foo"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"start_key": []string{`"bar"`},
+						"end_key":   []string{`"foo"`},
+					},
+				},
+			},
+		},
+		{
+			name: "limit, skip, descending",
+			args: []string{"--" + kouch.FlagLimit, "10", "--" + kouch.FlagSkip, "5", "--" + kouch.FlagDescending},
+			expected: &kouch.Options{
+				Target: &kouch.Target{},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"limit":      []string{"10"},
+						"skip":       []string{"5"},
+						"descending": []string{"true"},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := allDbsCmd()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, &kouch.Config{})
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := allDbsOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestAllDbsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "no server root specified",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("keys without info", test.CmdTest{
+		Args:   []string{"http://foo.com/", "--" + kouch.FlagKeys, "foo,bar"},
+		Err:    "--keys requires --info",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`["_users","foo"]`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+`/_all_dbs?limit=2&start_key="_"`, nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL, "--" + kouch.FlagStartKey, "_", "--" + kouch.FlagLimit, "2"},
+			Stdout: `["_users","foo"]`,
+		}
+	})
+	tests.Add("info", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"key":"foo","info":{"db_name":"foo"}}]`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/_dbs_info", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL, "--" + flagInfo},
+			Stdout: `[{"info":{"db_name":"foo"},"key":"foo"}]`,
+		}
+	})
+	tests.Add("info with keys", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"key":"foo","info":{"db_name":"foo"}}]`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/_dbs_info" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"keys":["foo","bar"]}`), body); d != nil {
+				t.Errorf("Unexpected body:\n%s", d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL, "--" + flagInfo, "--" + kouch.FlagKeys, "foo,bar"},
+			Stdout: `[{"info":{"db_name":"foo"},"key":"foo"}]`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "all-dbs"}))
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// archiveFormat and archiveVersion identify the dump archive format. The
+// version must be incremented for any change which older versions of restore
+// could not read correctly.
+const (
+	archiveFormat  = "kouch-dump"
+	archiveVersion = 1
+)
+
+// Archive record types. An archive is a stream of JSON records, one per line.
+// The first record is the header, and the last is the trailer, which allows a
+// truncated archive to be detected.
+const (
+	recordHeader   = "header"
+	recordDoc      = "doc"
+	recordLocal    = "local"
+	recordSecurity = "security"
+	recordTrailer  = "end"
+)
+
+// Attachment modes
+const (
+	attachmentsNone   = "none"
+	attachmentsInline = "inline"
+	attachmentsFiles  = "files"
+)
+
+// archiveHeader describes the source of the archive.
+type archiveHeader struct {
+	Format       string          `json:"format"`
+	Version      int             `json:"version"`
+	KouchVersion string          `json:"kouch_version"`
+	Created      string          `json:"created"`
+	Server       string          `json:"server"`
+	Database     string          `json:"db"`
+	UpdateSeq    json.RawMessage `json:"update_seq,omitempty"`
+	DocCount     int64           `json:"doc_count"`
+	Cluster      *archiveCluster `json:"cluster,omitempty"`
+	Attachments  string          `json:"attachments"`
+}
+
+// archiveCluster holds the shard settings of the source database.
+type archiveCluster struct {
+	Q int `json:"q,omitempty"`
+	N int `json:"n,omitempty"`
+}
+
+// archiveRecord is a single line of an archive.
+type archiveRecord struct {
+	Type     string          `json:"type"`
+	Header   *archiveHeader  `json:"header,omitempty"`
+	Doc      json.RawMessage `json:"doc,omitempty"`
+	Security json.RawMessage `json:"security,omitempty"`
+	Docs     *int            `json:"docs,omitempty"`
+	Local    *int            `json:"local_docs,omitempty"`
+}
+
+// archiveAttachment is an attachment stored in a separate file, relative to
+// the attachments directory.
+type archiveAttachment struct {
+	ContentType string `json:"content_type"`
+	Digest      string `json:"digest,omitempty"`
+	Length      int64  `json:"length,omitempty"`
+	File        string `json:"file"`
+}
+
+// attachmentFile returns the file in which to store the named attachment of
+// revision rev of the document id, relative to the attachments directory. The
+// revision keeps the attachments of conflicting revisions apart. Each segment
+// is escaped, so that none can refer to a file outside the directory.
+func attachmentFile(id, rev, name string) string {
+	return filepath.Join(escapeFilename(id), escapeFilename(rev), escapeFilename(name))
+}
+
+// escapeFilename escapes s for use as a single path segment. The dot segments
+// are escaped too, as url.PathEscape leaves them unchanged.
+func escapeFilename(s string) string {
+	s = url.PathEscape(s)
+	if s == "." || s == ".." {
+		return strings.Replace(s, ".", "%2E", -1)
+	}
+	return s
+}
@@ -0,0 +1,396 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Dump-specific flags
+const (
+	flagAttachments    = "attachments"
+	flagAttachmentsDir = "attachments-dir"
+	flagLocal          = "local"
+	flagSecurity       = "security"
+)
+
+// now returns the current time, for the archive header. It is replaced in
+// tests.
+var now = time.Now
+
+func init() {
+	registry.Register([]string{"dump"}, dumpDbCmd)
+}
+
+func dumpDbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "database [target]",
+		Aliases: []string{"db"},
+		Short:   "Exports a database to an archive.",
+		Long: "Streams every document in the database to an archive, which may be loaded with restore database. " +
+			"Documents are fetched a page at a time, so databases of any size may be dumped.\n\n" +
+			"The archive is a stream of JSON records, one per line. The first record is a header, which describes the source server, database, and update sequence. " +
+			"The last record marks the end of the archive, so that a truncated archive is detected on restore. " +
+			"The archive is written in its own format, regardless of --" + kouch.FlagOutputFormat + ".\n\n" +
+			"Each document is stored with its revision history, and conflicting revisions are stored as well, so that restore reproduces the revision tree. " +
+			"Deleted documents are not included. Documents are fetched with _bulk_get, which requires CouchDB 2.0 or later.\n\n" +
+			"Attachments are stored in one of the following ways, according to --" + flagAttachments + ":\n\n" +
+			"  " + attachmentsInline + "   Base64-encoded, within the documents.\n" +
+			"  " + attachmentsFiles + "    As separate files, beneath the directory named by --" + flagAttachmentsDir + ".\n" +
+			"  " + attachmentsNone + "     Not at all.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: dumpDatabaseCmd,
+	}
+	f := cmd.Flags()
+	f.String(flagAttachments, attachmentsInline, "How to store attachments: "+attachmentsInline+", "+attachmentsFiles+" or "+attachmentsNone+".")
+	f.String(flagAttachmentsDir, "", "The directory in which to store attachments, with --"+flagAttachments+"="+attachmentsFiles+".")
+	f.Bool(flagLocal, false, "Include _local documents. Requires CouchDB 2.2 or later.")
+	f.Bool(flagSecurity, false, "Include the database security object.")
+	f.Int(kouch.FlagPageSize, util.DefaultPageSize, "The number of documents to fetch per request.")
+	return cmd
+}
+
+type dumpOpts struct {
+	*kouch.Options
+	attachments    string
+	attachmentsDir string
+	local          bool
+	security       bool
+	pageSize       int
+}
+
+func dumpDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*dumpOpts, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if o.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	opts := &dumpOpts{Options: o}
+	if opts.attachments, err = flags.GetString(flagAttachments); err != nil {
+		return nil, err
+	}
+	if opts.attachmentsDir, err = flags.GetString(flagAttachmentsDir); err != nil {
+		return nil, err
+	}
+	switch opts.attachments {
+	case attachmentsInline, attachmentsNone:
+	case attachmentsFiles:
+		if opts.attachmentsDir == "" {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is required with --%s=%s", flagAttachmentsDir, flagAttachments, attachmentsFiles)
+		}
+	default:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s'", flagAttachments, opts.attachments)
+	}
+	if opts.local, err = flags.GetBool(flagLocal); err != nil {
+		return nil, err
+	}
+	if opts.security, err = flags.GetBool(flagSecurity); err != nil {
+		return nil, err
+	}
+	if opts.pageSize, err = flags.GetInt(kouch.FlagPageSize); err != nil {
+		return nil, err
+	}
+	if opts.pageSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", kouch.FlagPageSize)
+	}
+	return opts, nil
+}
+
+func dumpDatabaseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := dumpDatabaseOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	d := &dumper{dumpOpts: o, c: c}
+	header, err := d.header(ctx)
+	if err != nil {
+		return err
+	}
+	return util.StreamPages(ctx, d.pages(ctx, header))
+}
+
+type dumper struct {
+	*dumpOpts
+	c                *chttp.Client
+	docs, localCount int
+}
+
+// header fetches the database metadata, and builds the archive header.
+func (d *dumper) header(ctx context.Context) (json.RawMessage, error) {
+	var info struct {
+		UpdateSeq json.RawMessage `json:"update_seq"`
+		DocCount  int64           `json:"doc_count"`
+		Cluster   *archiveCluster `json:"cluster"`
+	}
+	opts := *d.Options.Options
+	opts.Query = nil
+	if _, err := d.c.DoJSON(ctx, http.MethodGet, util.DatabasePath(d.Options), &opts, &info); err != nil {
+		return nil, err
+	}
+	return json.Marshal(archiveRecord{
+		Type: recordHeader,
+		Header: &archiveHeader{
+			Format:       archiveFormat,
+			Version:      archiveVersion,
+			KouchVersion: kouch.Version,
+			Created:      now().UTC().Format(time.RFC3339),
+			Server:       d.Root,
+			Database:     d.Database,
+			UpdateSeq:    info.UpdateSeq,
+			DocCount:     info.DocCount,
+			Cluster:      info.Cluster,
+			Attachments:  d.attachments,
+		},
+	})
+}
+
+// pages returns a function which returns successive pages of archive records,
+// for use with util.StreamPages.
+func (d *dumper) pages(ctx context.Context, header json.RawMessage) func() ([]json.RawMessage, bool, error) {
+	type phase func() ([]json.RawMessage, bool, error)
+	once := func(fn func() (json.RawMessage, error)) phase {
+		return func() ([]json.RawMessage, bool, error) {
+			rec, err := fn()
+			return []json.RawMessage{rec}, false, err
+		}
+	}
+	phases := []phase{once(func() (json.RawMessage, error) { return header, nil })}
+	phases = append(phases, d.docPages(ctx, "/_all_docs", recordDoc, &d.docs))
+	if d.local {
+		phases = append(phases, d.docPages(ctx, "/_local_docs", recordLocal, &d.localCount))
+	}
+	if d.security {
+		phases = append(phases, once(func() (json.RawMessage, error) { return d.securityRecord(ctx) }))
+	}
+	phases = append(phases, once(func() (json.RawMessage, error) {
+		rec := archiveRecord{Type: recordTrailer, Docs: &d.docs}
+		if d.local {
+			rec.Local = &d.localCount
+		}
+		return json.Marshal(rec)
+	}))
+	return func() ([]json.RawMessage, bool, error) {
+		rows, more, err := phases[0]()
+		if err != nil {
+			return nil, false, err
+		}
+		if !more {
+			phases = phases[1:]
+		}
+		return rows, len(phases) > 0, nil
+	}
+}
+
+// docPages pages through the documents of path, converting each row to an
+// archive record of type recType. Each regular document is stored with its
+// revision history, and with any conflicting revisions as separate records.
+func (d *dumper) docPages(ctx context.Context, path, recType string, count *int) func() ([]json.RawMessage, bool, error) {
+	o := *d.Options
+	o.Options = &chttp.Options{}
+	o.Query().Set("include_docs", "true")
+	switch {
+	case recType == recordDoc:
+		o.Query().Set("conflicts", "true")
+	case d.attachments == attachmentsInline:
+		o.Query().Set("attachments", "true")
+	}
+	next := util.Pager(ctx, d.c, util.DatabasePath(d.Options)+path, d.pageSize, &o)
+	return func() ([]json.RawMessage, bool, error) {
+		rows, more, err := next()
+		if err != nil {
+			return nil, false, err
+		}
+		docs := make([]json.RawMessage, 0, len(rows))
+		for _, row := range rows {
+			var r struct {
+				Doc json.RawMessage `json:"doc"`
+			}
+			if e := json.Unmarshal(row, &r); e != nil {
+				return nil, false, errors.WrapExitError(chttp.ExitWeirdReply, e)
+			}
+			if len(r.Doc) == 0 || string(r.Doc) == "null" {
+				continue
+			}
+			docs = append(docs, r.Doc)
+		}
+		if recType == recordDoc {
+			if docs, err = d.leaves(ctx, docs); err != nil {
+				return nil, false, err
+			}
+		}
+		records := make([]json.RawMessage, 0, len(docs))
+		for _, doc := range docs {
+			doc, err := d.attachmentsFor(ctx, doc)
+			if err != nil {
+				return nil, false, err
+			}
+			rec, err := json.Marshal(archiveRecord{Type: recType, Doc: doc})
+			if err != nil {
+				return nil, false, err
+			}
+			records = append(records, rec)
+			*count++
+		}
+		return records, more, nil
+	}
+}
+
+// leaves fetches the winning and conflicting revisions of docs, as returned by
+// _all_docs with conflicts=true, from _bulk_get. The revisions are fetched with
+// their revision history, so that restoring them with new_edits=false
+// reproduces the revision tree.
+func (d *dumper) leaves(ctx context.Context, docs []json.RawMessage) ([]json.RawMessage, error) {
+	type leaf struct {
+		ID  string `json:"id"`
+		Rev string `json:"rev"`
+	}
+	var leaves []leaf
+	for _, doc := range docs {
+		var meta struct {
+			ID        string   `json:"_id"`
+			Rev       string   `json:"_rev"`
+			Conflicts []string `json:"_conflicts"`
+		}
+		if e := json.Unmarshal(doc, &meta); e != nil {
+			return nil, errors.WrapExitError(chttp.ExitWeirdReply, e)
+		}
+		leaves = append(leaves, leaf{ID: meta.ID, Rev: meta.Rev})
+		for _, rev := range meta.Conflicts {
+			leaves = append(leaves, leaf{ID: meta.ID, Rev: rev})
+		}
+	}
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+	opts := *d.Options.Options
+	opts.Query = url.Values{"revs": []string{"true"}}
+	if d.attachments == attachmentsInline {
+		opts.Query.Set("attachments", "true")
+	}
+	opts.Body = chttp.EncodeBody(map[string]interface{}{"docs": leaves})
+	var res struct {
+		Results []struct {
+			Docs []struct {
+				OK    json.RawMessage `json:"ok"`
+				Error *struct {
+					ID     string `json:"id"`
+					Rev    string `json:"rev"`
+					Error  string `json:"error"`
+					Reason string `json:"reason"`
+				} `json:"error"`
+			} `json:"docs"`
+		} `json:"results"`
+	}
+	if _, err := d.c.DoJSON(ctx, http.MethodPost, util.DatabasePath(d.Options)+"/_bulk_get", &opts, &res); err != nil {
+		return nil, err
+	}
+	result := make([]json.RawMessage, 0, len(leaves))
+	for _, r := range res.Results {
+		for _, doc := range r.Docs {
+			if e := doc.Error; e != nil {
+				return nil, errors.NewExitError(chttp.ExitNotRetrieved, "Failed to fetch revision %s of %s: %s: %s", e.Rev, e.ID, e.Error, e.Reason)
+			}
+			result = append(result, doc.OK)
+		}
+	}
+	return result, nil
+}
+
+// attachmentsFor applies the attachment mode to doc. Inline attachments are
+// left as fetched. Otherwise, attachments are removed, or saved to files and
+// replaced with references to the files.
+func (d *dumper) attachmentsFor(ctx context.Context, doc json.RawMessage) (json.RawMessage, error) {
+	if d.attachments == attachmentsInline {
+		return doc, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	atts, ok := fields["_attachments"]
+	if !ok {
+		return doc, nil
+	}
+	if d.attachments == attachmentsNone {
+		delete(fields, "_attachments")
+		return json.Marshal(fields)
+	}
+	var id, rev string
+	_ = json.Unmarshal(fields["_id"], &id)
+	_ = json.Unmarshal(fields["_rev"], &rev)
+	var stubs map[string]archiveAttachment
+	if err := json.Unmarshal(atts, &stubs); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	for name, stub := range stubs {
+		stub.File = attachmentFile(id, rev, name)
+		if err := d.saveAttachment(ctx, id, rev, name, stub.File); err != nil {
+			return nil, err
+		}
+		stubs[name] = stub
+	}
+	var err error
+	if fields["_attachments"], err = json.Marshal(stubs); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// saveAttachment fetches the named attachment, and writes it to file, relative
+// to the attachments directory.
+func (d *dumper) saveAttachment(ctx context.Context, id, rev, name, file string) error {
+	o := *d.Options
+	o.Target = &kouch.Target{Database: d.Database, Document: id, Filename: name}
+	opts := &chttp.Options{Query: url.Values{"rev": []string{rev}}}
+	res, err := d.c.DoReq(ctx, http.MethodGet, util.AttPath(&o), opts)
+	if err != nil {
+		return err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	path := filepath.Join(d.attachmentsDir, file)
+	if e := os.MkdirAll(filepath.Dir(path), 0755); e != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, e)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	if _, e := io.Copy(f, res.Body); e != nil {
+		_ = f.Close()
+		return errors.WrapExitError(chttp.ExitWriteError, e)
+	}
+	return errors.WrapExitError(chttp.ExitWriteError, f.Close())
+}
+
+func (d *dumper) securityRecord(ctx context.Context) (json.RawMessage, error) {
+	var security json.RawMessage
+	opts := *d.Options.Options
+	opts.Query = nil
+	if _, err := d.c.DoJSON(ctx, http.MethodGet, util.DatabasePath(d.Options)+"/_security", &opts, &security); err != nil {
+		return nil, err
+	}
+	return json.Marshal(archiveRecord{Type: recordSecurity, Security: security})
+}
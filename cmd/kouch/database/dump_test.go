@@ -0,0 +1,234 @@
+package database
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/dump"
+)
+
+// dumpServer serves a database with the documents bar, which has an
+// attachment, and baz, which has the conflicting revisions 2-b and 2-c.
+func dumpServer(t *testing.T) *httptest.Server {
+	leaves := map[string]string{
+		"bar/1-a": `"_id":"bar","_rev":"1-a","_revisions":{"start":1,"ids":["a"]}`,
+		"baz/2-b": `"_id":"baz","_rev":"2-b","_revisions":{"start":2,"ids":["b","x"]}`,
+		"baz/2-c": `"_id":"baz","_rev":"2-c","_revisions":{"start":2,"ids":["c","x"]}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/foo":
+			_, _ = w.Write([]byte(`{"db_name":"foo","doc_count":2,"update_seq":"2-xxx","cluster":{"q":8,"n":3,"w":2,"r":2}}`))
+		case "/foo/_all_docs":
+			if q.Get("include_docs") != "true" || q.Get("conflicts") != "true" || q.Get("limit") != "2" || q.Get("attachments") != "" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			if q.Get("start_key") == "" {
+				_, _ = w.Write([]byte(`{"rows":[{"id":"bar","key":"bar","doc":{"_id":"bar","_rev":"1-a"}},{"id":"baz","key":"baz"}]}`))
+				return
+			}
+			if q.Get("start_key") != `"baz"` || q.Get("start_key_doc_id") != "baz" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"rows":[{"id":"baz","key":"baz","doc":{"_id":"baz","_rev":"2-b","_conflicts":["2-c"]}}]}`))
+		case "/foo/_bulk_get":
+			if r.Method != http.MethodPost || q.Get("revs") != "true" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+			}
+			var req struct {
+				Docs []struct {
+					ID  string `json:"id"`
+					Rev string `json:"rev"`
+				} `json:"docs"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			results := make([]string, len(req.Docs))
+			for i, doc := range req.Docs {
+				leaf, ok := leaves[doc.ID+"/"+doc.Rev]
+				if !ok {
+					results[i] = `{"id":"` + doc.ID + `","docs":[{"error":{"id":"` + doc.ID + `","rev":"` + doc.Rev + `","error":"not_found","reason":"missing"}}]}`
+					continue
+				}
+				if doc.ID == "bar" {
+					leaf += `,"_attachments":{"a.txt":{"content_type":"text/plain","digest":"md5-xxx","length":3,"stub":true}}`
+					if q.Get("attachments") == "true" {
+						leaf = strings.Replace(leaf, `"stub":true`, `"data":"Zm9v"`, 1)
+					}
+				}
+				results[i] = `{"id":"` + doc.ID + `","docs":[{"ok":{` + leaf + `}}]}`
+			}
+			_, _ = w.Write([]byte(`{"results":[` + strings.Join(results, ",") + `]}`))
+		case "/foo/_local_docs":
+			_, _ = w.Write([]byte(`{"rows":[{"id":"_local/x","key":"_local/x","doc":{"_id":"_local/x","_rev":"0-1"}}]}`))
+		case "/foo/_security":
+			_, _ = w.Write([]byte(`{"admins":{"names":["bob"]}}`))
+		case "/foo/bar/a.txt":
+			if q.Get("rev") != "1-a" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("foo"))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestDumpDatabaseCmd(t *testing.T) {
+	origNow := now
+	now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { now = origNow }()
+	header := func(url, atts string) string {
+		return `{"type":"header","header":{"format":"kouch-dump","version":1,"kouch_version":"0.0.1-prerelease","created":"2020-01-02T03:04:05Z",` +
+			`"server":"` + url + `","db":"foo","update_seq":"2-xxx","doc_count":2,"cluster":{"q":8,"n":3},"attachments":"` + atts + `"}}` + "\n"
+	}
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--root", "http://foo.com/"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid attachments mode", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "--" + flagAttachments, "some"},
+		Err:    "Invalid --attachments 'some'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("files without dir", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "--" + flagAttachments, attachmentsFiles},
+		Err:    "--attachments-dir is required with --attachments=files",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("inline", func(t *testing.T) interface{} {
+		s := dumpServer(t)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--page-size", "1", "--" + flagLocal, "--" + flagSecurity},
+			Stdout: header(s.URL, "inline") +
+				`{"type":"doc","doc":{"_id":"bar","_rev":"1-a","_revisions":{"start":1,"ids":["a"]},"_attachments":{"a.txt":{"content_type":"text/plain","digest":"md5-xxx","length":3,"data":"Zm9v"}}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-b","_revisions":{"start":2,"ids":["b","x"]}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-c","_revisions":{"start":2,"ids":["c","x"]}}}` + "\n" +
+				`{"type":"local","doc":{"_id":"_local/x","_rev":"0-1"}}` + "\n" +
+				`{"type":"security","security":{"admins":{"names":["bob"]}}}` + "\n" +
+				`{"type":"end","docs":3,"local_docs":1}` + "\n",
+		}
+	})
+	tests.Add("no attachments", func(t *testing.T) interface{} {
+		s := dumpServer(t)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--page-size", "1", "--" + flagAttachments, attachmentsNone},
+			Stdout: header(s.URL, "none") +
+				`{"type":"doc","doc":{"_id":"bar","_rev":"1-a","_revisions":{"start":1,"ids":["a"]}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-b","_revisions":{"start":2,"ids":["b","x"]}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-c","_revisions":{"start":2,"ids":["c","x"]}}}` + "\n" +
+				`{"type":"end","docs":3}` + "\n",
+		}
+	})
+	tests.Add("missing revision", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/foo":
+				_, _ = w.Write([]byte(`{"db_name":"foo","doc_count":1,"update_seq":"1-xxx"}`))
+			case "/foo/_all_docs":
+				_, _ = w.Write([]byte(`{"rows":[{"id":"bar","key":"bar","doc":{"_id":"bar","_rev":"1-a"}}]}`))
+			case "/foo/_bulk_get":
+				_, _ = w.Write([]byte(`{"results":[{"id":"bar","docs":[{"error":{"id":"bar","rev":"1-a","error":"not_found","reason":"missing"}}]}]}`))
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo"},
+			Stdout: `{"type":"header","header":{"format":"kouch-dump","version":1,"kouch_version":"0.0.1-prerelease","created":"2020-01-02T03:04:05Z",` +
+				`"server":"` + s.URL + `","db":"foo","update_seq":"1-xxx","doc_count":1,"attachments":"inline"}}`,
+			Err:    "Failed to fetch revision 1-a of bar: not_found: missing",
+			Status: chttp.ExitNotRetrieved,
+		}
+	})
+	t.Run("attachment files", func(t *testing.T) {
+		s := dumpServer(t)
+		defer s.Close()
+		dir, err := ioutil.TempDir("", "kouch-dump")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir) // nolint: errcheck
+		test.ValidateCmdTest([]string{"dump", "db"})(t, test.CmdTest{
+			Args: []string{s.URL + "/foo", "--page-size", "1", "--" + flagAttachments, attachmentsFiles, "--" + flagAttachmentsDir, dir},
+			Stdout: header(s.URL, "files") +
+				`{"type":"doc","doc":{"_attachments":{"a.txt":{"content_type":"text/plain","digest":"md5-xxx","length":3,"file":"bar/1-a/a.txt"}},"_id":"bar","_rev":"1-a","_revisions":{"start":1,"ids":["a"]}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-b","_revisions":{"start":2,"ids":["b","x"]}}}` + "\n" +
+				`{"type":"doc","doc":{"_id":"baz","_rev":"2-c","_revisions":{"start":2,"ids":["c","x"]}}}` + "\n" +
+				`{"type":"end","docs":3}` + "\n",
+		})
+		content, err := ioutil.ReadFile(filepath.Join(dir, "bar", "1-a", "a.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "foo" {
+			t.Errorf("Unexpected attachment content: %s", content)
+		}
+	})
+	t.Run("attachment files with dot id", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/foo":
+				_, _ = w.Write([]byte(`{"db_name":"foo","doc_count":1,"update_seq":"1-xxx"}`))
+			case "/foo/_all_docs":
+				_, _ = w.Write([]byte(`{"rows":[{"id":"..","key":"..","doc":{"_id":"..","_rev":"1-a"}}]}`))
+			case "/foo/_bulk_get":
+				_, _ = w.Write([]byte(`{"results":[{"id":"..","docs":[{"ok":{"_id":"..","_rev":"1-a","_attachments":{"..":{"content_type":"text/plain","stub":true}}}}]}]}`))
+			default:
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("foo"))
+			}
+		}))
+		defer s.Close()
+		parent, err := ioutil.TempDir("", "kouch-dump")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(parent) // nolint: errcheck
+		dir := filepath.Join(parent, "atts")
+		test.ValidateCmdTest([]string{"dump", "db"})(t, test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagAttachments, attachmentsFiles, "--" + flagAttachmentsDir, dir},
+			Stdout: `{"type":"header","header":{"format":"kouch-dump","version":1,"kouch_version":"0.0.1-prerelease","created":"2020-01-02T03:04:05Z",` +
+				`"server":"` + s.URL + `","db":"foo","update_seq":"1-xxx","doc_count":1,"attachments":"files"}}` + "\n" +
+				`{"type":"doc","doc":{"_attachments":{"..":{"content_type":"text/plain","file":"%2E%2E/1-a/%2E%2E"}},"_id":"..","_rev":"1-a"}}` + "\n" +
+				`{"type":"end","docs":1}` + "\n",
+		})
+		files, err := ioutil.ReadDir(parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name() != "atts" {
+			t.Errorf("Attachment written outside of --attachments-dir")
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, "%2E%2E", "1-a", "%2E%2E"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "foo" {
+			t.Errorf("Unexpected attachment content: %s", content)
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"dump", "db"}))
+}
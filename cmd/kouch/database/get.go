@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getDbCmd)
+}
+
+func getDbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "database [target]",
+		Aliases: []string{"db"},
+		Short:   "Fetches database metadata.",
+		Long: "Fetches information about a database, such as the document count, sizes, update sequence, and cluster configuration.\n\n" +
+			"If the database does not exist, kouch exits with status " + strconv.Itoa(kouch.ExitNotFound) + ". Combined with --" + kouch.FlagHead + ", this may be used to test for the existence of a database:\n\n" +
+			"  kouch get database -" + kouch.FlagShortHead + " foo || kouch create database foo\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getDatabaseCmd,
+	}
+	cmd.PersistentFlags().BoolP(kouch.FlagHead, kouch.FlagShortHead, false, "Fetch the headers only.")
+	return cmd
+}
+
+func getDatabaseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getDatabaseOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getDatabase(ctx, o)
+}
+
+func getDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	return util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+}
+
+func getDatabase(ctx context.Context, o *kouch.Options) error {
+	if o.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	err := util.ChttpDo(ctx, http.MethodGet, util.DatabasePath(o), o)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		return errors.WrapExitError(kouch.ExitNotFound, err)
+	}
+	return err
+}
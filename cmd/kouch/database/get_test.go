@@ -0,0 +1,73 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetDatabaseCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--" + kouch.FlagServerRoot, "http://foo.com/"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"db_name":"oink","doc_count":3,"cluster":{"q":8,"n":3}}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/oink", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/oink", "-F", "yaml"},
+			Stdout: "cluster:\n  \"n\": 3\n  q: 8\ndb_name: oink\ndoc_count: 3",
+		}
+	})
+	tests.Add("head", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Header: http.Header{
+				"Content-Type": []string{"application/json"},
+				"Date":         []string{"Mon, 20 Aug 2018 08:55:52 GMT"},
+			},
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "HEAD", s.URL+"/oink", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/oink", "--" + kouch.FlagHead},
+			Stdout: "Content-Type: application/json\r\n" +
+				"Date: Mon, 20 Aug 2018 08:55:52 GMT\r\n",
+		}
+	})
+	tests.Add("head, not found", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 404,
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/oink", "--" + kouch.FlagHead},
+			Err:    "Not Found",
+			Status: kouch.ExitNotFound,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "database"}))
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getPurgedInfosLimitCmd)
+	registry.Register([]string{"put"}, putPurgedInfosLimitCmd)
+}
+
+func getPurgedInfosLimitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "purged-infos-limit [target]",
+		Short: "Fetches the purged infos limit of a database.",
+		Long: "Fetches the number of purge requests the database retains. Requires CouchDB 2.2 or later.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getPurgedInfosLimit,
+	}
+}
+
+func putPurgedInfosLimitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "purged-infos-limit [target]",
+		Short: "Sets the purged infos limit of a database.",
+		Long: "Sets the number of purge requests the database retains, read from the input. Requires CouchDB 2.2 or later.\n\n" +
+			"  kouch put purged-infos-limit foo -d 1000\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: putPurgedInfosLimit,
+	}
+}
+
+func purgedInfosLimitOpts(ctx context.Context, cmd *cobra.Command) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+	if o.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	return o, nil
+}
+
+func getPurgedInfosLimit(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := purgedInfosLimitOpts(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.DatabasePath(o)+"/_purged_infos_limit", o)
+}
+
+func putPurgedInfosLimit(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := purgedInfosLimitOpts(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadAll(kouch.Input(ctx))
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	limit, err := strconv.Atoi(string(bytes.TrimSpace(src)))
+	if err != nil || limit < 1 {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid purged infos limit '%s'", bytes.TrimSpace(src))
+	}
+	o.Options.Body = chttp.EncodeBody(limit)
+	return util.ChttpDo(ctx, http.MethodPut, util.DatabasePath(o)+"/_purged_infos_limit", o)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/put"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetPurgedInfosLimitCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--root", "http://foo.com/"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader("1000\n")),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_purged_infos_limit", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo"},
+			Stdout: "1000\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "purged-infos-limit"}))
+}
+
+func TestPutPurgedInfosLimitCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("invalid limit", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", "lots"},
+		Err:    "Invalid purged infos limit 'lots'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut || r.URL.Path != "/foo/_purged_infos_limit" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.TrimSpace(string(body)) != "500" {
+				t.Errorf("Unexpected body: %s", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", " 500\n"},
+			Stdout: `{"ok":true}` + "\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"put", "purged-infos-limit"}))
+}
@@ -0,0 +1,428 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Restore-specific flags
+const (
+	flagCreate     = "create"
+	flagBatchSize  = "batch-size"
+	flagCheckpoint = "checkpoint"
+)
+
+const defaultBatchSize = 1000
+
+func init() {
+	registry.Register([]string{"restore"}, restoreDbCmd)
+}
+
+func restoreDbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "database [target]",
+		Aliases: []string{"db"},
+		Short:   "Imports a database from an archive.",
+		Long: "Reads an archive created by dump database from the input, and uploads the documents to the target database with _bulk_docs. " +
+			"Documents are stored with their existing revisions, as replication does, so restoring the same documents twice is harmless.\n\n" +
+			"With --" + flagCreate + ", the database is first created, with the shard settings of the source database. " +
+			"The security object, if included in the archive, is restored.\n\n" +
+			"Progress is reported on stderr after each batch. With --" + flagCheckpoint + ", the number of documents restored is recorded in the named file after each batch, " +
+			"and an interrupted restore resumes after the last recorded batch when run again with the same file.\n\n" +
+			"A summary is output once the archive has been restored. If the server rejected any document, kouch exits with status " + strconv.Itoa(kouch.ExitBulkErrors) + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: restoreDatabaseCmd,
+	}
+	f := cmd.Flags()
+	f.Bool(flagCreate, false, "Create the database, with the shard settings recorded in the archive.")
+	f.Int(flagBatchSize, defaultBatchSize, "The number of documents to upload per request.")
+	f.String(flagCheckpoint, "", "A file in which to record progress, to resume an interrupted restore.")
+	f.String(flagAttachmentsDir, "", "The directory containing the attachments, for an archive dumped with --"+flagAttachments+"="+attachmentsFiles+".")
+	return cmd
+}
+
+type restorer struct {
+	*kouch.Options
+	c              *chttp.Client
+	create         bool
+	batchSize      int
+	checkpoint     string
+	attachmentsDir string
+
+	header  *archiveHeader
+	batch   []json.RawMessage
+	read    int
+	skip    int
+	local   int
+	failed  int
+	errors  []bulkError
+	trailer *archiveRecord
+}
+
+// bulkError is the server's response for a rejected document.
+type bulkError struct {
+	ID     string `json:"id"`
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// restoreCheckpoint records the progress of a restore.
+type restoreCheckpoint struct {
+	Database string `json:"db"`
+	Created  string `json:"created"`
+	Docs     int    `json:"docs"`
+}
+
+func restoreDatabaseOpts(ctx context.Context, flags *pflag.FlagSet) (*restorer, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if o.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	r := &restorer{Options: o}
+	if r.create, err = flags.GetBool(flagCreate); err != nil {
+		return nil, err
+	}
+	if r.batchSize, err = flags.GetInt(flagBatchSize); err != nil {
+		return nil, err
+	}
+	if r.batchSize < 1 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", flagBatchSize)
+	}
+	if r.checkpoint, err = flags.GetString(flagCheckpoint); err != nil {
+		return nil, err
+	}
+	if r.attachmentsDir, err = flags.GetString(flagAttachmentsDir); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func restoreDatabaseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	r, err := restoreDatabaseOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if r.c, err = r.NewClient(); err != nil {
+		return err
+	}
+	if e := r.restore(ctx, kouch.Input(ctx)); e != nil {
+		return e
+	}
+	if e := util.OutputValue(ctx, r.summary()); e != nil {
+		return e
+	}
+	if r.failed > 0 {
+		return errors.NewExitError(kouch.ExitBulkErrors, "%d of %d documents failed", r.failed, r.read-r.skip)
+	}
+	return nil
+}
+
+func (r *restorer) restore(ctx context.Context, in io.Reader) error {
+	dec := json.NewDecoder(in)
+	if err := r.readHeader(dec); err != nil {
+		return err
+	}
+	if err := r.readCheckpoint(); err != nil {
+		return err
+	}
+	if r.create {
+		if err := r.createDatabase(ctx); err != nil {
+			return err
+		}
+	}
+	for r.trailer == nil {
+		var rec archiveRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return errors.NewExitError(chttp.ExitReadError, "Archive is truncated: the end record is missing")
+			}
+			return errors.WrapExitError(chttp.ExitReadError, err)
+		}
+		if err := r.record(ctx, &rec); err != nil {
+			return err
+		}
+	}
+	if r.trailer.Docs != nil && *r.trailer.Docs != r.read {
+		return errors.NewExitError(chttp.ExitReadError, "Archive is corrupt: %d documents read, but %d expected", r.read, *r.trailer.Docs)
+	}
+	return nil
+}
+
+func (r *restorer) readHeader(dec *json.Decoder) error {
+	var rec archiveRecord
+	if err := dec.Decode(&rec); err != nil || rec.Type != recordHeader || rec.Header == nil || rec.Header.Format != archiveFormat {
+		return errors.NewExitError(chttp.ExitReadError, "Input is not a kouch dump archive")
+	}
+	if rec.Header.Version > archiveVersion {
+		return errors.NewExitError(chttp.ExitReadError, "Unsupported archive version %d; upgrade kouch to restore it", rec.Header.Version)
+	}
+	if rec.Header.Attachments == attachmentsFiles && r.attachmentsDir == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s is required to restore an archive with attachments stored in files", flagAttachmentsDir)
+	}
+	r.header = rec.Header
+	return nil
+}
+
+func (r *restorer) record(ctx context.Context, rec *archiveRecord) error {
+	switch rec.Type {
+	case recordDoc:
+		r.read++
+		if r.read <= r.skip {
+			return nil
+		}
+		doc, err := r.inlineAttachments(rec.Doc)
+		if err != nil {
+			return err
+		}
+		r.batch = append(r.batch, doc)
+		if len(r.batch) >= r.batchSize {
+			return r.flush(ctx)
+		}
+	case recordLocal:
+		if err := r.flush(ctx); err != nil {
+			return err
+		}
+		r.local++
+		return r.putLocal(ctx, rec.Doc)
+	case recordSecurity:
+		if err := r.flush(ctx); err != nil {
+			return err
+		}
+		return r.put(ctx, util.DatabasePath(r.Options)+"/_security", rec.Security)
+	case recordTrailer:
+		r.trailer = rec
+		return r.flush(ctx)
+	default:
+		return errors.NewExitError(chttp.ExitReadError, "Unknown archive record type '%s'", rec.Type)
+	}
+	return nil
+}
+
+// flush uploads the current batch, then records the progress.
+func (r *restorer) flush(ctx context.Context) error {
+	if len(r.batch) == 0 {
+		return nil
+	}
+	opts := *r.Options.Options
+	opts.Query = nil
+	opts.Body = chttp.EncodeBody(map[string]interface{}{"docs": r.batch, "new_edits": false})
+	var results []bulkError
+	if _, err := r.c.DoJSON(ctx, http.MethodPost, util.DatabasePath(r.Options)+"/_bulk_docs", &opts, &results); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			r.failed++
+			r.errors = append(r.errors, res)
+		}
+	}
+	r.batch = r.batch[:0]
+	fmt.Fprintf(os.Stderr, "Restored %d of %d documents\n", r.read, r.header.DocCount) // nolint: errcheck
+	return r.writeCheckpoint()
+}
+
+func (r *restorer) createDatabase(ctx context.Context) error {
+	opts := *r.Options.Options
+	opts.Query = url.Values{}
+	if cluster := r.header.Cluster; cluster != nil {
+		if cluster.Q > 0 {
+			opts.Query.Set("q", strconv.Itoa(cluster.Q))
+		}
+		if cluster.N > 0 {
+			opts.Query.Set("n", strconv.Itoa(cluster.N))
+		}
+	}
+	_, err := r.c.DoError(ctx, http.MethodPut, util.DatabasePath(r.Options), &opts)
+	if kivik.StatusCode(err) == kivik.StatusPreconditionFailed {
+		// The database already exists, as when resuming a restore.
+		return nil
+	}
+	return err
+}
+
+func (r *restorer) put(ctx context.Context, path string, body json.RawMessage) error {
+	opts := *r.Options.Options
+	opts.Query = nil
+	opts.Body = chttp.EncodeBody(body)
+	_, err := r.c.DoError(ctx, http.MethodPut, path, &opts)
+	return err
+}
+
+// putLocal stores a _local document, which cannot be stored with _bulk_docs.
+// Its revision is replaced with the current revision in the target database,
+// if any.
+func (r *restorer) putLocal(ctx context.Context, doc json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	o := *r.Options
+	target := *r.Target
+	o.Target = &target
+	if err := json.Unmarshal(fields["_id"], &o.Document); err != nil {
+		return errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	o.Options = &chttp.Options{}
+	delete(fields, "_rev")
+	rev, err := util.FetchRev(ctx, &o)
+	switch {
+	case kivik.StatusCode(err) == kivik.StatusNotFound:
+	case err != nil:
+		return err
+	case rev != "":
+		fields["_rev"], _ = json.Marshal(rev)
+	}
+	body, _ := json.Marshal(fields)
+	return r.put(ctx, util.DocPath(&o), body)
+}
+
+// inlineAttachments prepares the attachments of doc for upload. Attachments
+// stored in files are read and encoded, and metadata which would be checked
+// against the content, such as the digest, is removed.
+func (r *restorer) inlineAttachments(doc json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	atts, ok := fields["_attachments"]
+	if !ok {
+		return doc, nil
+	}
+	var stubs map[string]struct {
+		ContentType string `json:"content_type"`
+		RevPos      int    `json:"revpos,omitempty"`
+		Data        string `json:"data"`
+		File        string `json:"file"`
+	}
+	if err := json.Unmarshal(atts, &stubs); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	inline := make(map[string]interface{}, len(stubs))
+	for name, stub := range stubs {
+		if stub.File != "" {
+			path, err := attachmentPath(r.attachmentsDir, stub.File)
+			if err != nil {
+				return nil, err
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, errors.WrapExitError(chttp.ExitReadError, err)
+			}
+			stub.Data = base64.StdEncoding.EncodeToString(content)
+		}
+		att := map[string]interface{}{"content_type": stub.ContentType, "data": stub.Data}
+		if stub.RevPos > 0 {
+			att["revpos"] = stub.RevPos
+		}
+		inline[name] = att
+	}
+	var err error
+	if fields["_attachments"], err = json.Marshal(inline); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// attachmentPath resolves file, as read from an archive, against the
+// attachments directory. Absolute paths, and paths which lead outside the
+// directory, are rejected, so that a crafted archive cannot upload arbitrary
+// local files.
+func attachmentPath(dir, file string) (string, error) {
+	clean := filepath.Clean(file)
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || clean == "." ||
+		clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.NewExitError(chttp.ExitReadError, "Invalid attachment file '%s': must be within --%s", file, flagAttachmentsDir)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+func (r *restorer) readCheckpoint() error {
+	if r.checkpoint == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(r.checkpoint)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	var cp restoreCheckpoint
+	if e := json.Unmarshal(content, &cp); e != nil {
+		return errors.NewExitError(chttp.ExitReadError, "Invalid checkpoint file %s: %s", r.checkpoint, e)
+	}
+	if cp.Database != r.header.Database || cp.Created != r.header.Created {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Checkpoint file %s belongs to a different archive", r.checkpoint)
+	}
+	r.skip = cp.Docs
+	return nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint file, so that an
+// interruption never leaves it partially written.
+func (r *restorer) writeCheckpoint() error {
+	if r.checkpoint == "" {
+		return nil
+	}
+	content, _ := json.Marshal(restoreCheckpoint{
+		Database: r.header.Database,
+		Created:  r.header.Created,
+		Docs:     r.read,
+	})
+	tmp, err := ioutil.TempFile(filepath.Dir(r.checkpoint), "."+filepath.Base(r.checkpoint))
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	_, err = tmp.Write(append(content, '\n'))
+	if e := tmp.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.checkpoint)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return nil
+}
+
+func (r *restorer) summary() map[string]interface{} {
+	out := map[string]interface{}{
+		"ok":     r.read - r.skip - r.failed,
+		"failed": r.failed,
+	}
+	if r.skip > 0 {
+		out["skipped"] = r.skip
+	}
+	if r.local > 0 {
+		out["local_docs"] = r.local
+	}
+	if len(r.errors) > 0 {
+		out["errors"] = r.errors
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/restore"
+)
+
+const testArchive = `{"type":"header","header":{"format":"kouch-dump","version":1,"created":"2020-01-02T03:04:05Z","server":"http://foo.com","db":"foo","doc_count":2,"cluster":{"q":8,"n":3},"attachments":"inline"}}
+{"type":"doc","doc":{"_id":"bar","_rev":"1-a","_attachments":{"a.txt":{"content_type":"text/plain","revpos":1,"digest":"md5-xxx","length":3,"data":"Zm9v"}}}}
+{"type":"doc","doc":{"_id":"baz","_rev":"1-b"}}
+{"type":"local","doc":{"_id":"_local/x","_rev":"0-1","n":1}}
+{"type":"security","security":{"admins":{"names":["bob"]}}}
+{"type":"end","docs":2,"local_docs":1}
+`
+
+// restoreServer records each request as a line containing the method, the
+// path and query, and the body. _bulk_docs requests are answered with
+// bulkResponse.
+func restoreServer(t *testing.T, bulkResponse string, requests *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		*requests = append(*requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+strings.TrimSpace(string(body))))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/foo/_bulk_docs":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(bulkResponse))
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+}
+
+func TestRestoreDatabaseCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--root", "http://foo.com/", "-d", testArchive},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("not an archive", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"_id":"foo"}`},
+		Err:    "Input is not a kouch dump archive",
+		Status: chttp.ExitReadError,
+	})
+	tests.Add("newer version", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"type":"header","header":{"format":"kouch-dump","version":2}}`},
+		Err:    "Unsupported archive version 2; upgrade kouch to restore it",
+		Status: chttp.ExitReadError,
+	})
+	tests.Add("files without dir", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"type":"header","header":{"format":"kouch-dump","version":1,"attachments":"files"}}`},
+		Err:    "--attachments-dir is required to restore an archive with attachments stored in files",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("truncated", func(t *testing.T) interface{} {
+		var requests []string
+		s := restoreServer(t, `[]`, &requests)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", strings.Join(strings.Split(testArchive, "\n")[0:3], "\n")},
+			Err:    "Archive is truncated: the end record is missing",
+			Status: chttp.ExitReadError,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"restore", "db"}))
+
+	t.Run("success", func(t *testing.T) {
+		var requests []string
+		s := restoreServer(t, `[]`, &requests)
+		defer s.Close()
+		test.ValidateCmdTest([]string{"restore", "db"})(t, test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", testArchive, "--" + flagCreate, "--" + flagBatchSize, "1"},
+			Stdout: `{"failed":0,"local_docs":1,"ok":2}` + "\n",
+			Stderr: "Restored 1 of 2 documents\nRestored 2 of 2 documents\n",
+		})
+		expected := []string{
+			"PUT /foo?n=3&q=8",
+			`POST /foo/_bulk_docs {"docs":[{"_attachments":{"a.txt":{"content_type":"text/plain","data":"Zm9v","revpos":1}},"_id":"bar","_rev":"1-a"}],"new_edits":false}`,
+			`POST /foo/_bulk_docs {"docs":[{"_id":"baz","_rev":"1-b"}],"new_edits":false}`,
+			"HEAD /foo/_local/x",
+			`PUT /foo/_local/x {"_id":"_local/x","n":1}`,
+			`PUT /foo/_security {"admins":{"names":["bob"]}}`,
+		}
+		if d := diff.Interface(expected, requests); d != nil {
+			t.Error(d)
+		}
+	})
+	t.Run("partial failure", func(t *testing.T) {
+		var requests []string
+		s := restoreServer(t, `[{"id":"bar","error":"forbidden","reason":"nope"}]`, &requests)
+		defer s.Close()
+		test.ValidateCmdTest([]string{"restore", "db"})(t, test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", testArchive},
+			Stdout: `{"errors":[{"error":"forbidden","id":"bar","reason":"nope"}],"failed":1,"local_docs":1,"ok":1}` + "\n",
+			Stderr: "Restored 2 of 2 documents\n",
+			Err:    "1 of 2 documents failed",
+			Status: kouch.ExitBulkErrors,
+		})
+	})
+	t.Run("resume", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "kouch-restore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir) // nolint: errcheck
+		checkpoint := filepath.Join(dir, "checkpoint")
+		if e := ioutil.WriteFile(checkpoint, []byte(`{"db":"foo","created":"2020-01-02T03:04:05Z","docs":1}`), 0600); e != nil {
+			t.Fatal(e)
+		}
+		var requests []string
+		s := restoreServer(t, `[]`, &requests)
+		defer s.Close()
+		test.ValidateCmdTest([]string{"restore", "db"})(t, test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", testArchive, "--" + flagCheckpoint, checkpoint},
+			Stdout: `{"failed":0,"local_docs":1,"ok":1,"skipped":1}` + "\n",
+			Stderr: "Restored 2 of 2 documents\n",
+		})
+		if requests[0] != `POST /foo/_bulk_docs {"docs":[{"_id":"baz","_rev":"1-b"}],"new_edits":false}` {
+			t.Errorf("Unexpected first request: %s", requests[0])
+		}
+		content, err := ioutil.ReadFile(checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"db":"foo","created":"2020-01-02T03:04:05Z","docs":2}` + "\n"; string(content) != expected {
+			t.Errorf("Unexpected checkpoint: %s", content)
+		}
+	})
+	t.Run("attachment outside dir", func(t *testing.T) {
+		parent, err := ioutil.TempDir("", "kouch-restore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(parent) // nolint: errcheck
+		dir := filepath.Join(parent, "atts")
+		if e := os.Mkdir(dir, 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e := ioutil.WriteFile(filepath.Join(parent, "secret"), []byte("secret"), 0600); e != nil {
+			t.Fatal(e)
+		}
+		archive := `{"type":"header","header":{"format":"kouch-dump","version":1,"created":"2020-01-02T03:04:05Z","db":"foo","doc_count":1,"attachments":"files"}}
+{"type":"doc","doc":{"_id":"bar","_rev":"1-a","_attachments":{"a.txt":{"content_type":"text/plain","file":"bar/../../secret"}}}}
+{"type":"end","docs":1}
+`
+		var requests []string
+		s := restoreServer(t, `[]`, &requests)
+		defer s.Close()
+		test.ValidateCmdTest([]string{"restore", "db"})(t, test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", archive, "--" + flagAttachmentsDir, dir},
+			Err:    "Invalid attachment file 'bar/../../secret': must be within --attachments-dir",
+			Status: chttp.ExitReadError,
+		})
+		if len(requests) != 0 {
+			t.Errorf("Unexpected requests: %v", requests)
+		}
+	})
+	t.Run("other archive checkpoint", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "kouch-restore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir) // nolint: errcheck
+		checkpoint := filepath.Join(dir, "checkpoint")
+		if e := ioutil.WriteFile(checkpoint, []byte(`{"db":"qux","created":"2020-01-02T03:04:05Z","docs":1}`), 0600); e != nil {
+			t.Fatal(e)
+		}
+		test.ValidateCmdTest([]string{"restore", "db"})(t, test.CmdTest{
+			Args:   []string{"http://foo.com/foo", "-d", testArchive, "--" + flagCheckpoint, checkpoint},
+			Err:    "Checkpoint file " + checkpoint + " belongs to a different archive",
+			Status: chttp.ExitFailedToInitialize,
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package documents
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, allDocsCmd("all-docs", "_all_docs", "Lists all documents in a database."))
+	registry.Register([]string{"get"}, allDocsCmd("design-docs", "_design_docs", "Lists all design documents in a database."))
+	registry.Register([]string{"get"}, allDocsCmd("local-docs", "_local_docs", "Lists all non-replicating local documents in a database."))
+}
+
+// allDocsCmd returns an InitFunc for a command which queries endpoint, one
+// of _all_docs, _design_docs, or _local_docs.
+func allDocsCmd(use, endpoint, short string) registry.InitFunc {
+	return func() *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   use + " [target]",
+			Short: short,
+			Long: short + " The results are fetched from /{db}/" + endpoint + ".\n\n" +
+				kouch.TargetHelpText(kouch.TargetDatabase),
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				ctx := kouch.GetContext(cmd)
+				o, err := allDocsOpts(ctx, cmd.Flags())
+				if err != nil {
+					return err
+				}
+				return allDocs(ctx, endpoint, o, cmd.Flags())
+			},
+		}
+		f := cmd.Flags()
+		f.Bool(flagIncludeDocs, false, "Include the full content of the documents in the response.")
+		f.String(kouch.FlagKeys, "", "Return only documents that match the specified keys. Provide a JSON array, or a comma-separated list.")
+		f.String(kouch.FlagStartKey, "", "Return records starting with the specified document ID.")
+		f.String(kouch.FlagEndKey, "", "Stop returning records when the specified document ID is reached.")
+		f.Int(kouch.FlagLimit, 0, "Limit the number of returned documents. 0 means no limit.")
+		f.Int(kouch.FlagSkip, 0, "Skip this number of records before returning results.")
+		f.Bool(flagIncludeConflicts, false, "Include conflicts information in the response. Ignored unless --"+flagIncludeDocs+" is also set.")
+		f.Bool(flagUpdateSeq, false, "Include the update sequence in the response.")
+		f.Bool(kouch.FlagDescending, false, "Return the documents in descending order by key.")
+		f.Bool(kouch.FlagAll, false, "Fetch the entire key range, one page at a time, and output one row per line as newline-delimited JSON.")
+		f.Int(kouch.FlagPageSize, util.DefaultPageSize, "The number of rows to fetch per request, with --"+kouch.FlagAll+".")
+		return cmd
+	}
+}
+
+func allDocsOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range []string{kouch.FlagStartKey, kouch.FlagEndKey} {
+		if e := o.SetParamJSONString(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{kouch.FlagLimit, kouch.FlagSkip} {
+		if e := o.SetParamInt(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{flagIncludeDocs, flagIncludeConflicts, flagUpdateSeq, kouch.FlagDescending} {
+		if e := o.SetParamBool(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	return o, o.SetKeys(flags, kouch.JSONString)
+}
+
+func allDocs(ctx context.Context, endpoint string, o *kouch.Options, flags *pflag.FlagSet) error {
+	if err := validateDatabase(o.Target); err != nil {
+		return err
+	}
+	path := util.DatabasePath(o) + "/" + endpoint
+	all, err := flags.GetBool(kouch.FlagAll)
+	if err != nil {
+		return err
+	}
+	if all {
+		pageSize, err := flags.GetInt(kouch.FlagPageSize)
+		if err != nil {
+			return err
+		}
+		if o.Options.Body != nil || flags.Changed(kouch.FlagLimit) {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be combined with --%s or --%s", kouch.FlagAll, kouch.FlagKeys, kouch.FlagLimit)
+		}
+		return util.PageRows(ctx, path, pageSize, o)
+	}
+	method := http.MethodGet
+	if o.Options.Body != nil {
+		method = http.MethodPost
+	}
+	return util.ChttpDo(ctx, method, path, o)
+}
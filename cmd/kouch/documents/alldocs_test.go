@@ -0,0 +1,189 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/internal/util"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestAllDocsOpts(t *testing.T) {
+	type adoTest struct {
+		name     string
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}
+	tests := []adoTest{
+		{
+			name: "db from target",
+			args: []string{"http://foo.com/bar"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Root: "http://foo.com", Database: "bar"},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "keys encoded",
+			args: []string{"--" + kouch.FlagStartKey, "abc", "--" + kouch.FlagEndKey, `x"y`, "bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"start_key": []string{`"abc"`},
+						"end_key":   []string{`"x\"y"`},
+					},
+				},
+			},
+		},
+		{
+			name: "limit and skip",
+			args: []string{"--" + kouch.FlagLimit, "10", "--" + kouch.FlagSkip, "20", "bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"limit": []string{"10"},
+						"skip":  []string{"20"},
+					},
+				},
+			},
+		},
+	}
+	for _, flag := range []string{flagIncludeDocs, flagIncludeConflicts, flagUpdateSeq, kouch.FlagDescending} {
+		tests = append(tests, adoTest{
+			name: flag,
+			args: []string{"--" + flag, "bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{util.Param(flag): []string{"true"}},
+				},
+			},
+		})
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := allDocsCmd("all-docs", "_all_docs", "")()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, &kouch.Config{})
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := allDocsOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestAllDocsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("all with limit", test.CmdTest{
+		Args:   []string{"http://foo.com/bar", "--" + kouch.FlagAll, "--" + kouch.FlagLimit, "10"},
+		Err:    "--all may not be combined with --keys or --limit",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("all", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"total_rows":1,"offset":0,"rows":[{"id":"a","key":"a","value":{"rev":"1-x"}}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_all_docs?limit=11", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagAll, "--" + kouch.FlagPageSize, "10", "-F", "yaml"},
+			Stdout: `{"id":"a","key":"a","value":{"rev":"1-x"}}` + "\n",
+		}
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"total_rows":1,"offset":0,"rows":[{"id":"a","key":"a","value":{"rev":"1-x"}}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_all_docs?include_docs=true", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagIncludeDocs},
+			Stdout: `{"offset":0,"rows":[{"id":"a","key":"a","value":{"rev":"1-x"}}],"total_rows":1}`,
+		}
+	})
+	tests.Add("keys", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"rows":[]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/foo/_all_docs" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"keys":["a","b"]}`), body); d != nil {
+				t.Errorf("Unexpected body:\n%s", d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagKeys, "a,b"},
+			Stdout: `{"rows":[]}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "all-docs"}))
+}
+
+func TestDesignDocsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"rows":[]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_design_docs", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo"},
+			Stdout: `{"rows":[]}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "design-docs"}))
+}
@@ -0,0 +1,192 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Copy-doc specific flags
+const (
+	flagDestRev     = "dest-rev"
+	flagDestAutoRev = "dest-auto-rev"
+)
+
+func init() {
+	registry.Register([]string{"copy"}, copyDocCmd)
+}
+
+func copyDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [source] [destination]",
+		Aliases: []string{"doc"},
+		Short:   "Copies a single document.",
+		Long: "Copies the source document to the destination. The destination may be a document ID in the source database, " +
+			"{db}/{id}, or a full URL.\n\n" +
+			"Within a single database, the server-side COPY method is used. Across databases or servers, the source document " +
+			"and its attachments are fetched, then stored at the destination.\n\n" +
+			"To overwrite an existing destination document, provide its revision with --" + flagDestRev + ", or use --" + flagDestAutoRev + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		Annotations: map[string]string{kouch.AnnotationExtraArgs: ""},
+		RunE:        copyDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The source document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The source database. May be provided with the target in the format /{db}/{id}.")
+	f.StringP(kouch.FlagRev, kouch.FlagShortRev, "", "The revision of the source document to copy.")
+	f.String(flagDestRev, "", "The current revision of the destination document, to overwrite it.")
+	f.Bool(flagDestAutoRev, false, "Fetch the current rev of the destination before copying. Use with caution!")
+	f.Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
+	return cmd
+}
+
+func copyDocumentCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	o.Options.FullCommit, err = cmd.Flags().GetBool(kouch.FlagFullCommit)
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	if len(args) < 2 {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No destination provided")
+	}
+	dest, err := copyDestination(o, args[1])
+	if err != nil {
+		return err
+	}
+	if e := setDestRev(ctx, dest, cmd.Flags()); e != nil {
+		return e
+	}
+	if dest.Root == o.Root && dest.Database == o.Database {
+		return copyConflictError(copyDocument(ctx, o, dest))
+	}
+	return copyConflictError(transferDocument(ctx, o, dest))
+}
+
+// copyDestination parses the destination argument, filling in the server and
+// database from the source where omitted.
+func copyDestination(o *kouch.Options, src string) (*kouch.Options, error) {
+	t, err := kouch.ParseTarget(kouch.TargetDocument, src)
+	if err != nil {
+		return nil, err
+	}
+	if t.Document == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No destination document ID provided")
+	}
+	if t.Root == "" {
+		t.Root, t.User, t.Password = o.Root, o.User, o.Password
+		if t.Database == "" {
+			t.Database = o.Database
+		}
+	}
+	if t.Database == "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No destination database name provided")
+	}
+	dest := kouch.NewOptions()
+	dest.Target = t
+	dest.Options.FullCommit = o.Options.FullCommit
+	return dest, nil
+}
+
+// setDestRev sets the revision of the destination document, if one was
+// provided or requested.
+func setDestRev(ctx context.Context, dest *kouch.Options, flags *pflag.FlagSet) error {
+	rev, err := flags.GetString(flagDestRev)
+	if err != nil {
+		return err
+	}
+	autoRev, err := flags.GetBool(flagDestAutoRev)
+	if err != nil {
+		return err
+	}
+	if rev != "" && autoRev {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Must not use both --%s and --%s", flagDestRev, flagDestAutoRev)
+	}
+	if autoRev {
+		rev, err = util.FetchRev(ctx, dest)
+		if err != nil && kivik.StatusCode(err) != kivik.StatusNotFound {
+			return err
+		}
+	}
+	if rev != "" {
+		dest.Query().Set("rev", rev)
+	}
+	return nil
+}
+
+// copyDocument copies the document within a database, with the COPY method.
+func copyDocument(ctx context.Context, o, dest *kouch.Options) error {
+	o.Options.Destination = chttp.EncodeDocID(dest.Document)
+	if rev := dest.Query().Get("rev"); rev != "" {
+		o.Options.Destination += "?rev=" + rev
+	}
+	return util.ChttpDo(ctx, "COPY", util.DocPath(o), o)
+}
+
+// transferDocument copies the document to another database or server, by
+// fetching it with its attachments, and storing it at the destination.
+func transferDocument(ctx context.Context, o, dest *kouch.Options) error {
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	opts := *o.Options
+	opts.Query = url.Values{"attachments": []string{"true"}}
+	if rev := o.Query().Get("rev"); rev != "" {
+		opts.Query.Set("rev", rev)
+	}
+	var doc map[string]json.RawMessage
+	if _, e := c.DoJSON(ctx, http.MethodGet, util.DocPath(o), &opts, &doc); e != nil {
+		return e
+	}
+	delete(doc, "_rev")
+	id, _ := json.Marshal(dest.Document)
+	doc["_id"] = id
+	if atts, ok := doc["_attachments"]; ok {
+		if doc["_attachments"], err = inlineAttachments(atts); err != nil {
+			return err
+		}
+	}
+	if rev := dest.Query().Get("rev"); rev != "" {
+		doc["_rev"], _ = json.Marshal(rev)
+		dest.Query().Del("rev")
+	}
+	dest.Options.Body = chttp.EncodeBody(doc)
+	return util.ChttpDo(ctx, http.MethodPut, util.DocPath(dest), dest)
+}
+
+// inlineAttachments strips the fetched attachment metadata which does not
+// apply to the new document, leaving only the content type and data.
+func inlineAttachments(src json.RawMessage) (json.RawMessage, error) {
+	var atts map[string]struct {
+		ContentType string `json:"content_type"`
+		Data        string `json:"data"`
+	}
+	if err := json.Unmarshal(src, &atts); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	return json.Marshal(atts)
+}
+
+func copyConflictError(err error) error {
+	if kivik.StatusCode(err) != kivik.StatusConflict {
+		return err
+	}
+	return errors.NewExitError(kouch.ExitConflict, "Document update conflict: the destination exists. Provide its current revision with --%s, or use --%s.", flagDestRev, flagDestAutoRev)
+}
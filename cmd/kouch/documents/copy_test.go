@@ -0,0 +1,130 @@
+package documents
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
+)
+
+func TestCopyDocCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no destination", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar"},
+		Err:    "No destination provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("both dest revs", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar", "baz", "--" + flagDestRev, "1-xyz", "--" + flagDestAutoRev},
+		Err:    "Must not use both --dest-rev and --dest-auto-rev",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("copy", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "COPY" || r.URL.Path != "/foo/bar" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			if rev := r.URL.Query().Get("rev"); rev != "1-abc" {
+				t.Errorf("Unexpected source rev: %s", rev)
+			}
+			if dest := r.Header.Get("Destination"); dest != "_design/baz?rev=2-xyz" {
+				t.Errorf("Unexpected destination: %s", dest)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"ok":true,"id":"_design/baz","rev":"3-xyz"}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "_design/baz", "--rev", "1-abc", "--" + flagDestRev, "2-xyz", "-F", "yaml"},
+			Stdout: "id: _design/baz\nok: true\nrev: 3-xyz",
+		}
+	})
+	tests.Add("dest auto rev", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.Method {
+			case http.MethodHead:
+				if r.URL.Path != "/foo/baz" {
+					t.Errorf("Unexpected HEAD path: %s", r.URL.Path)
+				}
+				w.Header().Set("ETag", `"2-xyz"`)
+				w.WriteHeader(200)
+			case "COPY":
+				if dest := r.Header.Get("Destination"); dest != "baz?rev=2-xyz" {
+					t.Errorf("Unexpected destination: %s", dest)
+				}
+				w.WriteHeader(201)
+				_, _ = w.Write([]byte(`{"ok":true,"id":"baz","rev":"3-xyz"}`))
+			default:
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "/foo/baz", "--" + flagDestAutoRev, "-F", "yaml"},
+			Stdout: "id: baz\nok: true\nrev: 3-xyz",
+		}
+	})
+	tests.Add("other database", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/foo/bar":
+				if att := r.URL.Query().Get("attachments"); att != "true" {
+					t.Errorf("Unexpected attachments param: %s", att)
+				}
+				_, _ = w.Write([]byte(`{"_id":"bar","_rev":"1-abc","n":12345678901234567890,"_attachments":{"foo.txt":{"content_type":"text/plain","revpos":1,"digest":"md5-xxx","data":"Zm9v"}}}`))
+			case r.Method == http.MethodPut && r.URL.Path == "/qux/baz":
+				var doc interface{}
+				if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+					t.Fatal(err)
+				}
+				expected := json.RawMessage(`{"_id":"baz","_rev":"2-xyz","n":12345678901234567890,"_attachments":{"foo.txt":{"content_type":"text/plain","data":"Zm9v"}}}`)
+				if d := diff.AsJSON(expected, doc); d != nil {
+					t.Error(d)
+				}
+				w.WriteHeader(201)
+				_, _ = w.Write([]byte(`{"ok":true,"id":"baz","rev":"3-xyz"}`))
+			default:
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(500)
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "qux/baz", "--" + flagDestRev, "2-xyz", "-F", "yaml"},
+			Stdout: "id: baz\nok: true\nrev: 3-xyz",
+		}
+	})
+	tests.Add("conflict", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 409,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error":"conflict","reason":"Document update conflict."}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "baz"},
+			Err:    "Document update conflict: the destination exists. Provide its current revision with --dest-rev, or use --dest-auto-rev.",
+			Status: kouch.ExitConflict,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"copy", "doc"}))
+}
@@ -0,0 +1,121 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Create-doc specific flags
+const (
+	flagGenerateID = "generate-id"
+)
+
+func init() {
+	registry.Register([]string{"create"}, createDocCmd)
+}
+
+func createDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target]",
+		Aliases: []string{"doc"},
+		Short:   "Creates a new document, with a generated ID.",
+		Long: "Creates a new document in the specified database. The server assigns the document ID, unless the document contains an _id.\n\n" +
+			"With --" + flagGenerateID + ", the ID is instead taken from the server's UUIDs before the document is saved, so that it is known even if the response is lost.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: createDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.Bool(flagGenerateID, false, "Fetch a UUID from the server to use as the document ID, before saving the document.")
+	f.Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
+	f.Bool(flagBatch, false, "Store document in batch mode.")
+	return cmd
+}
+
+func createDocumentCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := createDocumentOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateDatabase(o.Target); e != nil {
+		return e
+	}
+	generateID, err := cmd.Flags().GetBool(flagGenerateID)
+	if err != nil {
+		return err
+	}
+	if !generateID {
+		o.Options.Body = kouch.Input(ctx)
+		return util.ChttpDo(ctx, http.MethodPost, util.DatabasePath(o), o)
+	}
+	doc, fields, err := readDoc(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := fields["_id"]; ok {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Document already has an _id; --%s may not be used", flagGenerateID)
+	}
+	uuids, err := util.UUIDs(ctx, o, 1)
+	if err != nil {
+		return err
+	}
+	if len(uuids) == 0 {
+		return errors.NewExitError(chttp.ExitWeirdReply, "No UUID returned by the server")
+	}
+	o.Document = uuids[0]
+	o.Options.Body = chttp.EncodeBody(json.RawMessage(withID(doc, uuids[0])))
+	return util.ChttpDo(ctx, http.MethodPut, util.DocPath(o), o)
+}
+
+func createDocumentOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	o.Options.FullCommit, err = flags.GetBool(kouch.FlagFullCommit)
+	if err != nil {
+		return nil, err
+	}
+	return o, setBatch(o, flags)
+}
+
+// readDoc reads a single JSON object from the input, and returns it unchanged,
+// along with its top-level fields.
+func readDoc(ctx context.Context) ([]byte, map[string]json.RawMessage, error) {
+	src, err := ioutil.ReadAll(kouch.Input(ctx))
+	if err != nil {
+		return nil, nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	src = bytes.TrimSpace(src)
+	if len(src) == 0 || src[0] != '{' {
+		return nil, nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid document: not an object")
+	}
+	var fields map[string]json.RawMessage
+	if e := json.Unmarshal(src, &fields); e != nil {
+		return nil, nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid document: %s", e)
+	}
+	return src, fields, nil
+}
+
+// withID inserts the _id field at the start of doc, which must be a JSON
+// object without an _id. The rest of the document is left as it is.
+func withID(doc []byte, id string) []byte {
+	field, _ := json.Marshal(id)
+	field = append([]byte(`{"_id":`), field...)
+	rest := bytes.TrimSpace(doc[1:])
+	if rest[0] != '}' {
+		field = append(field, ',')
+	}
+	return append(field, rest...)
+}
@@ -0,0 +1,83 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/create"
+)
+
+func TestCreateDocCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"-d", "{}"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("server id", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/foo" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != `{"foo":"bar"}` {
+				t.Errorf("Unexpected body: %s", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"ok":true,"id":"abc","rev":"1-xyz"}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", `{"foo":"bar"}`, "-F", "yaml"},
+			Stdout: "id: abc\nok: true\nrev: 1-xyz",
+		}
+	})
+	tests.Add("generate id with _id", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"_id":"bar"}`, "--" + flagGenerateID},
+		Err:    "Document already has an _id; --generate-id may not be used",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("generate id invalid doc", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `[]`, "--" + flagGenerateID},
+		Err:    "Invalid document: not an object",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("generate id null doc", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `null`, "--" + flagGenerateID},
+		Err:    "Invalid document: not an object",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("generate id", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/_uuids":
+				_, _ = w.Write([]byte(`{"uuids":["abc123"]}`))
+			case r.Method == http.MethodPut && r.URL.Path == "/foo/abc123":
+				body, _ := ioutil.ReadAll(r.Body)
+				if expected := `{"_id":"abc123","foo":"bar","bar":9007199254740993}`; string(body) != expected {
+					t.Errorf("Unexpected body: %s", body)
+				}
+				w.WriteHeader(201)
+				_, _ = w.Write([]byte(`{"ok":true,"id":"abc123","rev":"1-xyz"}`))
+			default:
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(500)
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", `{"foo":"bar","bar":9007199254740993}`, "--" + flagGenerateID, "-F", "yaml"},
+			Stdout: "id: abc123\nok: true\nrev: 1-xyz",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"create", "doc"}))
+}
@@ -0,0 +1,65 @@
+package documents
+
+import (
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register([]string{"delete"}, deleteDocCmd)
+}
+
+func deleteDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target]",
+		Aliases: []string{"doc"},
+		Short:   "Deletes a single document.",
+		Long: "Marks the specified document as deleted.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		RunE: deleteDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	f.StringP(kouch.FlagRev, kouch.FlagShortRev, "", "The current revision of the document to delete.")
+	f.Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
+	f.BoolP(kouch.FlagAutoRev, kouch.FlagShortAutoRev, false, "Fetch the current rev before deletion. Use with caution!")
+
+	f.Bool(flagBatch, false, "Delete document in batch mode.")
+	return cmd
+}
+
+func deleteDocumentOpts(cmd *cobra.Command, _ []string) (*kouch.Options, error) {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+
+	o.Options.FullCommit, err = cmd.Flags().GetBool(kouch.FlagFullCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	if e := setBatch(o, cmd.Flags()); e != nil {
+		return nil, e
+	}
+
+	return o, nil
+}
+
+func deleteDocumentCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := deleteDocumentOpts(cmd, args)
+	if err != nil {
+		return err
+	}
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	return kouch.ConflictError(util.ChttpDo(ctx, http.MethodDelete, util.DocPath(o), o))
+}
@@ -0,0 +1,163 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/internal/util"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestDeleteDocumentOpts(t *testing.T) {
+	type ddoTest struct {
+		name     string
+		conf     *kouch.Config
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}
+	tests := []ddoTest{
+		{
+			name:   "duplicate id",
+			args:   []string{"--" + kouch.FlagDocument, "foo", "bar"},
+			err:    "Must not use --" + kouch.FlagDocument + " and pass document ID as part of the target",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name: "full url target",
+			args: []string{"http://foo.com/foo/123"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{
+					Root:     "http://foo.com",
+					Database: "foo",
+					Document: "123",
+				},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "rev",
+			args: []string{"--" + kouch.FlagRev, "1-xyz", "docid"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Document: "docid"},
+				Options: &chttp.Options{
+					Query: url.Values{"rev": []string{"1-xyz"}},
+				},
+			},
+		},
+		{
+			name: "full commit",
+			args: []string{"--" + kouch.FlagFullCommit, "docid"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Document: "docid"},
+				Options: &chttp.Options{FullCommit: true},
+			},
+		},
+		{
+			name: "batch",
+			args: []string{"--" + flagBatch, "docid"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Document: "docid"},
+				Options: &chttp.Options{
+					Query: url.Values{util.Param(flagBatch): []string{"ok"}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.conf == nil {
+				test.conf = &kouch.Config{}
+			}
+			cmd := deleteDocCmd()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, test.conf)
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := deleteDocumentOpts(cmd, cmd.Flags().Args())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestDeleteDocCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true,"id":"bar","rev":"2-967a00dff5e02add41819138abb3284d"}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "DELETE", s.URL+"/foo/bar?rev=1-967a00dff5e02add41819138abb3284d", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-F", "yaml", "--rev", "1-967a00dff5e02add41819138abb3284d"},
+			Stdout: "id: bar\nok: true\nrev: 2-967a00dff5e02add41819138abb3284d",
+		}
+	})
+	tests.Add("auto rev", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Content-Type", "application/json")
+			if r.Method == http.MethodHead {
+				w.Header().Add("ETag", `"1-xyz"`)
+				w.WriteHeader(200)
+				return
+			}
+			if r.Method != http.MethodDelete {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if rev := r.URL.Query().Get("rev"); rev != "1-xyz" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+			w.WriteHeader(200)
+			_, _ = w.Write([]byte(`{"ok":true,"id":"bar","rev":"2-967a00dff5e02add41819138abb3284d"}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-F", "yaml", "--auto-rev"},
+			Stdout: "id: bar\nok: true\nrev: 2-967a00dff5e02add41819138abb3284d",
+		}
+	})
+	tests.Add("conflict", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 409,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error":"conflict","reason":"Document update conflict."}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--rev", "1-xyz"},
+			Err:    "Document update conflict: the revision is missing or not current. Provide the current revision with --rev, or use --auto-rev.",
+			Status: kouch.ExitConflict,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"delete", "doc"}))
+}
@@ -0,0 +1,374 @@
+package documents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/icza/dyno"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultEditor = "vi"
+
+func init() {
+	registry.Register([]string{"edit"}, editDocCmd)
+}
+
+func editDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target]",
+		Aliases: []string{"doc"},
+		Short:   "Edits a single document.",
+		Long: "Fetches a single document, opens it in $EDITOR, then saves the edited document.\n\n" +
+			"The document is rendered as YAML with --" + kouch.FlagOutputFormat + "=yaml, and as JSON otherwise. " +
+			"Comments added by kouch, above and below the marked lines, are ignored. To cancel the edit, save the file unchanged, or empty.\n\n" +
+			"If the document was updated by someone else in the meantime, the editor is re-opened with your edits, " +
+			"and, for comparison, the latest version from the server alongside your edits. Save again to overwrite the latest version.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		RunE: editDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	return cmd
+}
+
+func editDocumentCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	format, err := cmd.Flags().GetString(kouch.FlagOutputFormat)
+	if err != nil {
+		return err
+	}
+	return editDocument(ctx, o, &docEditor{yaml: format == "yaml"})
+}
+
+func editDocument(ctx context.Context, o *kouch.Options, e *docEditor) error {
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	doc, err := fetchDoc(ctx, c, o)
+	if err != nil {
+		return err
+	}
+	original, err := e.render(doc)
+	if err != nil {
+		return err
+	}
+	content := original
+	for {
+		edited, err := e.edit(content)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, original) || len(bytes.TrimSpace(stripComments(edited))) == 0 {
+			fmt.Fprintln(os.Stderr, "Edit cancelled, no changes made.") // nolint: errcheck
+			return nil
+		}
+		newDoc, err := e.parse(edited)
+		if err != nil {
+			content = append(header("Parsing failed: "+err.Error()), stripComments(edited)...)
+			continue
+		}
+		newDoc["_id"], newDoc["_rev"] = doc["_id"], doc["_rev"]
+		var result map[string]interface{}
+		opts := *o.Options
+		opts.Body = chttp.EncodeBody(newDoc)
+		_, err = c.DoJSON(ctx, http.MethodPut, util.DocPath(o), &opts, &result)
+		if err == nil {
+			return util.OutputValue(ctx, result)
+		}
+		if kivik.StatusCode(err) != kivik.StatusConflict {
+			return err
+		}
+		if doc, err = fetchDoc(ctx, c, o); err != nil {
+			return err
+		}
+		newDoc["_rev"] = doc["_rev"]
+		if content, err = e.conflict(doc, newDoc); err != nil {
+			return err
+		}
+	}
+}
+
+// fetchDoc fetches the current version of the document.
+func fetchDoc(ctx context.Context, c *chttp.Client, o *kouch.Options) (map[string]interface{}, error) {
+	v, err := fetchJSON(ctx, c, util.DocPath(o), o.Options)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewExitError(chttp.ExitWeirdReply, "document is not an object")
+	}
+	return doc, nil
+}
+
+// fetchJSON fetches path, and decodes the JSON response, preserving integers.
+func fetchJSON(ctx context.Context, c *chttp.Client, path string, opts *chttp.Options) (interface{}, error) {
+	v, err := fetchNumbers(ctx, c, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(v), nil
+}
+
+// fetchNumbers fetches path, and decodes the JSON response, with numbers
+// decoded as json.Number.
+func fetchNumbers(ctx context.Context, c *chttp.Client, path string, opts *chttp.Options) (interface{}, error) {
+	res, err := c.DoReq(ctx, http.MethodGet, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	var v interface{}
+	if err := decodeJSON(res.Body, &v); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	return v, nil
+}
+
+// normalizeNumbers converts json.Number values to int64 where possible, or
+// float64 otherwise, so that integers are rendered without exponents.
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = normalizeNumbers(v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = normalizeNumbers(v)
+		}
+	}
+	return v
+}
+
+// runEditor opens filename in the user's editor, and waits for it to exit.
+var runEditor = func(filename string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = defaultEditor
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], filename)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.NewExitError(chttp.ExitUnknownFailure, "Editor failed: %s", err)
+	}
+	return nil
+}
+
+// docEditor renders and parses documents as JSON, or as YAML.
+type docEditor struct {
+	yaml bool
+}
+
+func (e *docEditor) render(doc map[string]interface{}) ([]byte, error) {
+	if e.yaml {
+		return yaml.Marshal(doc)
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return append(out, '\n'), err
+}
+
+func (e *docEditor) parse(content []byte) (map[string]interface{}, error) {
+	content = stripComments(content)
+	if !e.yaml {
+		var doc map[string]interface{}
+		if err := decodeJSON(bytes.NewReader(content), &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, errors.New("document must be an object")
+		}
+		return doc, nil
+	}
+	var i interface{}
+	if err := yaml.Unmarshal(content, &i); err != nil {
+		return nil, err
+	}
+	doc, ok := dyno.ConvertMapI2MapS(i).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("document must be an object")
+	}
+	return doc, nil
+}
+
+// decodeJSON decodes a single JSON value from r into v. Numbers are decoded
+// as json.Number, so that they are written back unchanged.
+func decodeJSON(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after the document")
+	}
+	return nil
+}
+
+// edit writes content to a temporary file, opens it in the editor, and
+// returns the edited content.
+func (e *docEditor) edit(content []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "kouch-edit")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	filename := filepath.Join(dir, "document.json")
+	if e.yaml {
+		filename = filepath.Join(dir, "document.yaml")
+	}
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		return nil, err
+	}
+	if err := runEditor(filename); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// conflict renders the user's edits, followed by a comment which shows the
+// latest version from the server side by side with the edits.
+func (e *docEditor) conflict(latest, edited map[string]interface{}) ([]byte, error) {
+	theirs, err := e.render(latest)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := e.render(edited)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(header("The document was updated on the server while you were editing it. Your edits are below.\n" +
+		"Save to overwrite the latest version (shown on the left, at the end of this file) with your edits."))
+	buf.Write(ours)
+	buf.Write(footer(sideBySide(theirs, ours)))
+	return buf.Bytes(), nil
+}
+
+// sideBySide renders left and right in two columns. Lines which differ are
+// marked with '|', as by sdiff.
+func sideBySide(left, right []byte) string {
+	l, r := lines(left), lines(right)
+	width := 0
+	for _, line := range l {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	n := len(l)
+	if len(r) > n {
+		n = len(r)
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%-*s   %s\n", width, "server", "yours") // nolint: errcheck
+	for i := 0; i < n; i++ {
+		var a, b string
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(r) {
+			b = r[i]
+		}
+		sep := "   "
+		if a != b {
+			sep = " | "
+		}
+		fmt.Fprintf(buf, "%-*s%s%s\n", width, a, sep, b) // nolint: errcheck
+	}
+	return buf.String()
+}
+
+func lines(content []byte) []string {
+	var result []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		result = append(result, scanner.Text())
+	}
+	return result
+}
+
+// Markers which delimit the comments kouch adds to the file being edited.
+// Everything above commentsEnd, and below commentsStart, is discarded when the
+// file is read back. Other lines starting with '#' are left alone, as they may
+// be part of the document.
+const (
+	commentsEnd   = "# ---- Lines above this one are ignored ----"
+	commentsStart = "# ---- Lines below this one are ignored ----"
+)
+
+// header renders text as a comment to precede the document.
+func header(text string) []byte {
+	return append(comment(text), commentsEnd+"\n"...)
+}
+
+// footer renders text as a comment to follow the document.
+func footer(text string) []byte {
+	return append([]byte(commentsStart+"\n"), comment(text)...)
+}
+
+// comment prefixes each line of text with '# '.
+func comment(text string) []byte {
+	buf := &bytes.Buffer{}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		buf.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	return buf.Bytes()
+}
+
+// stripComments removes the header and footer comments, if present.
+func stripComments(content []byte) []byte {
+	ls := lines(content)
+	for i, line := range ls {
+		if strings.TrimSpace(line) == commentsEnd {
+			ls = ls[i+1:]
+			break
+		}
+	}
+	for i := len(ls) - 1; i >= 0; i-- {
+		if strings.TrimSpace(ls[i]) == commentsStart {
+			ls = ls[:i]
+			break
+		}
+	}
+	buf := &bytes.Buffer{}
+	for _, line := range ls {
+		buf.WriteString(line + "\n")
+	}
+	return buf.Bytes()
+}
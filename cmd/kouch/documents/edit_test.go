@@ -0,0 +1,257 @@
+package documents
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/edit"
+)
+
+// fakeEditor replaces runEditor for the duration of a test. Each call to the
+// editor consumes the next function in edits, which receives the content of
+// the file and returns the new content.
+func fakeEditor(t *testing.T, edits ...func(string) string) func() {
+	orig := runEditor
+	runEditor = func(filename string) error {
+		if len(edits) == 0 {
+			t.Fatal("Unexpected editor invocation")
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		edit := edits[0]
+		edits = edits[1:]
+		return ioutil.WriteFile(filename, []byte(edit(string(content))), 0600)
+	}
+	return func() {
+		runEditor = orig
+		if len(edits) > 0 {
+			t.Errorf("%d expected editor invocations did not happen", len(edits))
+		}
+	}
+}
+
+// editServer serves GET requests from docs, one per request, and responds to
+// PUT requests with the statuses in puts. The bodies of the PUT requests are
+// recorded in bodies.
+func editServer(t *testing.T, docs []string, puts []int, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if len(docs) == 0 {
+				t.Fatal("Unexpected GET")
+			}
+			_, _ = w.Write([]byte(docs[0]))
+			docs = docs[1:]
+		case http.MethodPut:
+			if len(puts) == 0 {
+				t.Fatal("Unexpected PUT")
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			*bodies = append(*bodies, string(body))
+			w.WriteHeader(puts[0])
+			if puts[0] == http.StatusConflict {
+				_, _ = w.Write([]byte(`{"error":"conflict","reason":"Document update conflict."}`))
+			} else {
+				_, _ = w.Write([]byte(`{"ok":true,"id":"bar","rev":"3-xyz"}`))
+			}
+			puts = puts[1:]
+		default:
+			t.Errorf("Unexpected method: %s", r.Method)
+		}
+	}))
+}
+
+func TestEditDocumentCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("unchanged", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t, func(s string) string { return s }))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`}, nil, &bodies)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stderr: "Edit cancelled, no changes made.\n",
+		}
+	})
+	tests.Add("json", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t, func(s string) string {
+			if !strings.Contains(s, `"foo": "bar"`) {
+				t.Errorf("Unexpected content: %s", s)
+			}
+			return strings.Replace(s, `"foo": "bar"`, `"foo": "baz", "count": 1000000`, 1)
+		}))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"baz","count":1000000}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("yaml", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t, func(s string) string {
+			if !strings.Contains(s, "foo: bar\n") {
+				t.Errorf("Unexpected content: %s", s)
+			}
+			return strings.Replace(s, "foo: bar", "foo: baz\n_rev: 9-ignored", 1)
+		}))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar","n":12345678}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"baz","n":12345678}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-F", "yaml"},
+			Stdout: "id: bar\nok: true\nrev: 3-xyz",
+		}
+	})
+	tests.Add("large integers", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t, func(s string) string {
+			return strings.Replace(s, `"foo": "bar"`, `"foo": "baz", "m": 9007199254740993`, 1)
+		}))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar","n":9007199254740993}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			if len(bodies) != 1 || !strings.Contains(bodies[0], `"m":9007199254740993`) || !strings.Contains(bodies[0], `"n":9007199254740993`) {
+				t.Errorf("Unexpected bodies: %v", bodies)
+			}
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("yaml hash lines", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t,
+			func(s string) string { return s + "notes: |\n  # not a comment\n  text\n" },
+		))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar","notes":"# not a comment\ntext\n"}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-F", "yaml"},
+			Stdout: "id: bar\nok: true\nrev: 3-xyz",
+		}
+	})
+	tests.Add("parse error", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t,
+			func(s string) string { return `{"foo":` },
+			func(s string) string {
+				if !strings.HasPrefix(s, "# Parsing failed: unexpected EOF\n") {
+					t.Errorf("Unexpected content: %s", s)
+				}
+				return strings.Replace(s, `{"foo":`, `{"foo":"qux"}`, 1)
+			},
+		))
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"qux"}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("conflict", func(t *testing.T) interface{} {
+		tests.Cleanup(fakeEditor(t,
+			func(s string) string { return strings.Replace(s, `"foo": "bar"`, `"foo": "mine"`, 1) },
+			func(s string) string {
+				for _, expected := range []string{
+					"# The document was updated on the server",
+					`"_rev": "2-abc"`,
+					`"foo": "mine"`,
+					`#   "foo": "theirs"  |   "foo": "mine"`,
+				} {
+					if !strings.Contains(s, expected) {
+						t.Errorf("Expected %q in content:\n%s", expected, s)
+					}
+				}
+				return s
+			},
+		))
+		var bodies []string
+		s := editServer(t, []string{
+			`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`,
+			`{"_id":"bar","_rev":"2-abc","foo":"theirs"}`,
+		}, []int{http.StatusConflict, http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{
+				`{"_id":"bar","_rev":"1-xyz","foo":"mine"}`,
+				`{"_id":"bar","_rev":"2-abc","foo":"mine"}`,
+			}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"edit", "document"}))
+}
+
+func checkBodies(t *testing.T, expected, bodies []string) {
+	if len(expected) != len(bodies) {
+		t.Errorf("Expected %d requests, got %d", len(expected), len(bodies))
+		return
+	}
+	for i, body := range bodies {
+		if d := diff.JSON([]byte(expected[i]), []byte(body)); d != nil {
+			t.Error(d)
+		}
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	left := []byte("{\n  \"a\": 1\n}\n")
+	right := []byte("{\n  \"a\": 2,\n  \"b\": 3\n}\n")
+	expected := "server     yours\n" +
+		"{          {\n" +
+		"  \"a\": 1 |   \"a\": 2,\n" +
+		"}        |   \"b\": 3\n" +
+		"         | }\n"
+	if d := diff.Text(expected, sideBySide(left, right)); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(`{"i":1000000,"f":1.5,"a":[2]}`)))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"i": int64(1000000), "f": 1.5, "a": []interface{}{int64(2)}}
+	if d := diff.Interface(expected, normalizeNumbers(doc)); d != nil {
+		t.Error(d)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/internal/util"
 
 	_ "github.com/go-kivik/kouch/cmd/kouch/get"
 	_ "github.com/go-kivik/kouch/cmd/kouch/root"
@@ -116,7 +117,7 @@ func TestGetDocumentOpts(t *testing.T) {
 			expected: &kouch.Options{
 				Target: &kouch.Target{Document: "docid"},
 				Options: &chttp.Options{
-					Query: url.Values{param(flagAttsSince): []string{`["foo","bar","baz"]`}},
+					Query: url.Values{util.Param(flagAttsSince): []string{`["foo","bar","baz"]`}},
 				},
 			},
 		},
@@ -126,7 +127,7 @@ func TestGetDocumentOpts(t *testing.T) {
 			expected: &kouch.Options{
 				Target: &kouch.Target{Document: "docid"},
 				Options: &chttp.Options{
-					Query: url.Values{param(flagOpenRevs): []string{`["foo","bar","baz"]`}},
+					Query: url.Values{util.Param(flagOpenRevs): []string{`["foo","bar","baz"]`}},
 				},
 			},
 		},
@@ -142,7 +143,7 @@ func TestGetDocumentOpts(t *testing.T) {
 			expected: &kouch.Options{
 				Target: &kouch.Target{Document: "docid"},
 				Options: &chttp.Options{
-					Query: url.Values{param(flag): []string{"true"}},
+					Query: url.Values{util.Param(flag): []string{"true"}},
 				},
 			},
 		})
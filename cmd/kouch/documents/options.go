@@ -1,11 +1,10 @@
 package documents
 
 import (
-	"strings"
-
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
 	"github.com/spf13/pflag"
 )
 
@@ -30,18 +29,30 @@ const (
 	flagNewEdits = "new-edits"
 )
 
-func param(flagName string) string {
-	return strings.Replace(flagName, "-", "_", -1)
-}
+// All-docs specific flags
+const (
+	flagIncludeDocs = "include-docs"
+	flagUpdateSeq   = "update-seq"
+)
 
 func setBatch(o *kouch.Options, f *pflag.FlagSet) error {
 	v, err := f.GetBool(flagBatch)
 	if err == nil && v {
-		o.Query().Add(param(flagBatch), "ok")
+		o.Query().Add(util.Param(flagBatch), "ok")
 	}
 	return err
 }
 
+func validateDatabase(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
+
 func validateTarget(t *kouch.Target) error {
 	if t.Filename != "" {
 		panic("non-nil filename")
@@ -0,0 +1,161 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/patch"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Patch-doc specific flags
+const (
+	flagPatchType = "type"
+	flagRetries   = "retries"
+)
+
+// Supported patch types
+const (
+	patchTypeAuto  = "auto"
+	patchTypeJSON  = "json"
+	patchTypeMerge = "merge"
+)
+
+func init() {
+	registry.Register([]string{"patch"}, patchDocCmd)
+}
+
+func patchDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target]",
+		Aliases: []string{"doc"},
+		Short:   "Applies a patch to a single document.",
+		Long: "Fetches a single document, applies the supplied patch, then saves the result with the fetched revision. " +
+			"If the document is updated by someone else in the meantime, the patch is re-applied to the latest version, up to --" + flagRetries + " times.\n\n" +
+			"The patch may be a JSON Patch (RFC 6902), which is an array of operations, or a JSON Merge Patch (RFC 7396), which is an object. " +
+			"By default, the type is determined by the patch itself.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		RunE: patchDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	f.String(flagPatchType, patchTypeAuto, "The patch type. One of: "+patchTypeAuto+", "+patchTypeJSON+" (RFC 6902), "+patchTypeMerge+" (RFC 7396).")
+	f.Int(flagRetries, 3, "The number of times to retry, when the update conflicts.")
+	f.Bool(kouch.FlagFullCommit, false, "Overrides server’s commit policy.")
+	return cmd
+}
+
+func patchDocumentCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	o.Options.FullCommit, err = cmd.Flags().GetBool(kouch.FlagFullCommit)
+	if err != nil {
+		return err
+	}
+	p, err := readPatch(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	retries, err := cmd.Flags().GetInt(flagRetries)
+	if err != nil {
+		return err
+	}
+	return patchDocument(ctx, o, p, retries)
+}
+
+// docPatch applies a patch to a decoded document.
+type docPatch func(doc interface{}) (interface{}, error)
+
+// readPatch reads the patch from the input.
+func readPatch(ctx context.Context, flags *pflag.FlagSet) (docPatch, error) {
+	patchType, err := flags.GetString(flagPatchType)
+	if err != nil {
+		return nil, err
+	}
+	src, err := ioutil.ReadAll(kouch.Input(ctx))
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	src = bytes.TrimSpace(src)
+	if len(src) == 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No patch provided")
+	}
+	if patchType == patchTypeAuto {
+		patchType = patchTypeMerge
+		if src[0] == '[' {
+			patchType = patchTypeJSON
+		}
+	}
+	switch patchType {
+	case patchTypeJSON:
+		var ops []patch.Operation
+		if e := json.Unmarshal(src, &ops); e != nil {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid JSON Patch: %s", e)
+		}
+		return func(doc interface{}) (interface{}, error) {
+			return patch.Apply(doc, ops)
+		}, nil
+	case patchTypeMerge:
+		var merge interface{}
+		if e := decodeJSON(bytes.NewReader(src), &merge); e != nil {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid merge patch: %s", e)
+		}
+		return func(doc interface{}) (interface{}, error) {
+			return patch.Merge(doc, merge), nil
+		}, nil
+	}
+	return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s'", flagPatchType, patchType)
+}
+
+func patchDocument(ctx context.Context, o *kouch.Options, p docPatch, retries int) error {
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		doc, err := fetchNumbers(ctx, c, util.DocPath(o), o.Options)
+		if err != nil {
+			return err
+		}
+		current, _ := doc.(map[string]interface{})
+		patched, err := p(doc)
+		if err != nil {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "Patch failed: %s", err)
+		}
+		newDoc, ok := patched.(map[string]interface{})
+		if !ok {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "Patch failed: the result is not an object")
+		}
+		newDoc["_id"], newDoc["_rev"] = current["_id"], current["_rev"]
+		var result interface{}
+		opts := *o.Options
+		opts.Body = chttp.EncodeBody(newDoc)
+		_, err = c.DoJSON(ctx, http.MethodPut, util.DocPath(o), &opts, &result)
+		if err == nil {
+			return util.OutputValue(ctx, result)
+		}
+		if kivik.StatusCode(err) != kivik.StatusConflict {
+			return err
+		}
+		if attempt >= retries {
+			return errors.NewExitError(kouch.ExitConflict, "Document update conflict: gave up after %d retries", retries)
+		}
+	}
+}
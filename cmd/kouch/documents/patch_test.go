@@ -0,0 +1,121 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/patch"
+)
+
+func TestPatchDocumentCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"-d", "{}"},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid type", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar", "-d", "{}", "--" + flagPatchType, "xml"},
+		Err:    "Invalid --type 'xml'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid json patch", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar", "-d", `{"op":"add"}`, "--" + flagPatchType, patchTypeJSON},
+		Err:    "Invalid JSON Patch: json: cannot unmarshal object into Go value of type []patch.Operation",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("merge patch", func(t *testing.T) interface{} {
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar","a":1}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"baz"}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--data-yaml", "foo: baz\na: null"},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("large integers", func(t *testing.T) interface{} {
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar","n":9007199254740993}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			if len(bodies) != 1 || !strings.Contains(bodies[0], `"m":9007199254740995`) || !strings.Contains(bodies[0], `"n":9007199254740993`) {
+				t.Errorf("Unexpected bodies: %v", bodies)
+			}
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-d", `{"foo":"baz","m":9007199254740995}`},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("json patch", func(t *testing.T) interface{} {
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","tags":["a"]}`}, []int{http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{`{"_id":"bar","_rev":"1-xyz","tags":["a","b"]}`}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-d", `[{"op":"add","path":"/tags/-","value":"b"}]`},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("failed test op", func(t *testing.T) interface{} {
+		var bodies []string
+		s := editServer(t, []string{`{"_id":"bar","_rev":"1-xyz","foo":"bar"}`}, nil, &bodies)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-d", `[{"op":"test","path":"/foo","value":"baz"}]`},
+			Err:    "Patch failed: operation 0 (test /foo): test failed",
+			Status: chttp.ExitFailedToInitialize,
+		}
+	})
+	tests.Add("retry on conflict", func(t *testing.T) interface{} {
+		var bodies []string
+		s := editServer(t, []string{
+			`{"_id":"bar","_rev":"1-xyz","n":1}`,
+			`{"_id":"bar","_rev":"2-xyz","n":2}`,
+		}, []int{http.StatusConflict, http.StatusCreated}, &bodies)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(func() {
+			checkBodies(t, []string{
+				`{"_id":"bar","_rev":"1-xyz","n":1,"x":true}`,
+				`{"_id":"bar","_rev":"2-xyz","n":2,"x":true}`,
+			}, bodies)
+		})
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-d", `{"x":true}`},
+			Stdout: `{"id":"bar","ok":true,"rev":"3-xyz"}`,
+		}
+	})
+	tests.Add("retries exhausted", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Method == http.MethodPut {
+				_, _ = ioutil.ReadAll(r.Body)
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"error":"conflict","reason":"Document update conflict."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"_id":"bar","_rev":"1-xyz"}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "-d", `{"x":true}`, "--" + flagRetries, "1"},
+			Err:    "Document update conflict: gave up after 1 retries",
+			Status: kouch.ExitConflict,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"patch", "document"}))
+}
@@ -0,0 +1,154 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Purge-doc specific flags
+const (
+	flagAllRevs = "all-revs"
+	flagDryRun  = "dry-run"
+)
+
+func init() {
+	registry.Register([]string{"purge"}, purgeDocCmd)
+}
+
+func purgeDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target] [id ...]",
+		Aliases: []string{"doc"},
+		Short:   "Permanently removes document revisions.",
+		Long: "Permanently removes the specified document revisions from the database, with _purge. Purged revisions cannot be recovered, and are not replicated. Requires CouchDB 2.2 or later.\n\n" +
+			"The revisions to purge are read from the input, as a JSON object mapping document IDs to arrays of revisions:\n\n" +
+			`  {"docid":["1-967a00dff5e02add41819138abb3284d"]}` + "\n\n" +
+			"With --" + flagAllRevs + ", every leaf revision of each document ID given after the target is purged instead.\n\n" +
+			"With --" + flagDryRun + ", the purge request is output, but not sent.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		Annotations: map[string]string{kouch.AnnotationExtraArgs: ""},
+		RunE:        purgeDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database.")
+	f.Bool(flagAllRevs, false, "Purge all leaf revisions of the documents named in the arguments.")
+	f.Bool(flagDryRun, false, "Output the purge request without sending it.")
+	return cmd
+}
+
+func purgeDocumentCmd(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateDatabase(o.Target); e != nil {
+		return e
+	}
+	req, err := purgeRequest(ctx, o, cmd.Flags(), args)
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool(flagDryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return util.OutputValue(ctx, req)
+	}
+	o.Options.Body = chttp.EncodeBody(req)
+	return util.ChttpDo(ctx, http.MethodPost, util.DatabasePath(o)+"/_purge", o)
+}
+
+// purgeRequest builds the purge request, either from the leaf revisions of
+// the named documents, or from the input.
+func purgeRequest(ctx context.Context, o *kouch.Options, flags *pflag.FlagSet, args []string) (map[string][]string, error) {
+	allRevs, err := flags.GetBool(flagAllRevs)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if len(args) > 1 {
+		ids = args[1:]
+	}
+	if !allRevs {
+		if len(ids) > 0 {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Document IDs may only be provided with --%s", flagAllRevs)
+		}
+		return readPurgeRequest(ctx)
+	}
+	if len(ids) == 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No document IDs provided")
+	}
+	req := make(map[string][]string, len(ids))
+	for _, id := range ids {
+		revs, err := leafRevs(ctx, o, id)
+		if err != nil {
+			return nil, err
+		}
+		req[id] = revs
+	}
+	return req, nil
+}
+
+func readPurgeRequest(ctx context.Context) (map[string][]string, error) {
+	src, err := ioutil.ReadAll(kouch.Input(ctx))
+	if err != nil {
+		return nil, errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	var req map[string][]string
+	if e := json.Unmarshal(src, &req); e != nil {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid purge request: %s", e)
+	}
+	if len(req) == 0 {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No documents to purge")
+	}
+	for id, revs := range req {
+		if len(revs) == 0 {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No revisions provided for document '%s'", id)
+		}
+	}
+	return req, nil
+}
+
+// leafRevs fetches the revisions of all leaves of the document's revision
+// tree, including deleted and conflicting leaves.
+func leafRevs(ctx context.Context, o *kouch.Options, id string) ([]string, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	doc := *o
+	doc.Target = &kouch.Target{Database: o.Database, Document: id}
+	opts := *o.Options
+	opts.Query = url.Values{"open_revs": []string{"all"}}
+	var leaves []struct {
+		OK *struct {
+			Rev string `json:"_rev"`
+		} `json:"ok"`
+	}
+	if _, e := c.DoJSON(ctx, http.MethodGet, util.DocPath(&doc), &opts, &leaves); e != nil {
+		return nil, e
+	}
+	var revs []string
+	for _, leaf := range leaves {
+		if leaf.OK != nil {
+			revs = append(revs, leaf.OK.Rev)
+		}
+	}
+	if len(revs) == 0 {
+		return nil, errors.NewExitError(kouch.ExitNotFound, "Document '%s' not found", id)
+	}
+	return revs, nil
+}
@@ -0,0 +1,116 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/purge"
+)
+
+func TestPurgeDocCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"-d", "{}"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("ids without all revs", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "bar"},
+		Err:    "Document IDs may only be provided with --all-revs",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("all revs without ids", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "--" + flagAllRevs},
+		Err:    "No document IDs provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid request", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `["bar"]`},
+		Err:    "Invalid purge request: json: cannot unmarshal array into Go value of type map[string][]string",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("empty request", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{}`},
+		Err:    "No documents to purge",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no revs", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"bar":[]}`},
+		Err:    "No revisions provided for document 'bar'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("dry run", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "-d", `{"bar":["1-abc"]}`, "--" + flagDryRun},
+		Stdout: `{"bar":["1-abc"]}` + "\n",
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/foo/_purge" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.TrimSpace(string(body)) != `{"bar":["1-abc","2-def"]}` {
+				t.Errorf("Unexpected body: %s", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"purge_seq":null,"purged":{"bar":["1-abc","2-def"]}}`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "-d", `{"bar":["1-abc","2-def"]}`, "-F", "yaml"},
+			Stdout: "purge_seq: null\npurged:\n  bar:\n  - 1-abc\n  - 2-def",
+		}
+	})
+	tests.Add("all revs", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/foo/bar":
+				if openRevs := r.URL.Query().Get("open_revs"); openRevs != "all" {
+					t.Errorf("Unexpected open_revs: %s", openRevs)
+				}
+				_, _ = w.Write([]byte(`[{"ok":{"_id":"bar","_rev":"2-def"}},{"ok":{"_id":"bar","_rev":"2-xyz","_deleted":true}}]`))
+			case r.Method == http.MethodGet && r.URL.Path == "/foo/baz":
+				_, _ = w.Write([]byte(`[{"ok":{"_id":"baz","_rev":"1-abc"}}]`))
+			case r.Method == http.MethodPost && r.URL.Path == "/foo/_purge":
+				body, _ := ioutil.ReadAll(r.Body)
+				if strings.TrimSpace(string(body)) != `{"bar":["2-def","2-xyz"],"baz":["1-abc"]}` {
+					t.Errorf("Unexpected body: %s", body)
+				}
+				w.WriteHeader(201)
+				_, _ = w.Write([]byte(`{"purge_seq":null,"purged":{"bar":["2-def","2-xyz"],"baz":["1-abc"]}}`))
+			default:
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(500)
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "bar", "baz", "--" + flagAllRevs},
+			Stdout: `{"purge_seq":null,"purged":{"bar":["2-def","2-xyz"],"baz":["1-abc"]}}` + "\n",
+		}
+	})
+	tests.Add("all revs missing doc", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"missing":"all"}]`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "bar", "--" + flagAllRevs},
+			Err:    "Document 'bar' not found",
+			Status: kouch.ExitNotFound,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"purge", "doc"}))
+}
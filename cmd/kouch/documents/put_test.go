@@ -13,6 +13,7 @@ import (
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
 	"github.com/go-kivik/kouch/internal/test"
+	"github.com/go-kivik/kouch/internal/util"
 
 	_ "github.com/go-kivik/kouch/cmd/kouch/put"
 	_ "github.com/go-kivik/kouch/cmd/kouch/root"
@@ -104,7 +105,7 @@ func TestPutDocumentOpts(t *testing.T) {
 			expected: &kouch.Options{
 				Target: &kouch.Target{Document: "docid"},
 				Options: &chttp.Options{
-					Query: url.Values{param(flagBatch): []string{"ok"}},
+					Query: url.Values{util.Param(flagBatch): []string{"ok"}},
 				},
 			},
 		},
@@ -114,7 +115,7 @@ func TestPutDocumentOpts(t *testing.T) {
 			expected: &kouch.Options{
 				Target: &kouch.Target{Document: "docid"},
 				Options: &chttp.Options{
-					Query: url.Values{param(flagNewEdits): []string{"false"}},
+					Query: url.Values{util.Param(flagNewEdits): []string{"false"}},
 				},
 			},
 		},
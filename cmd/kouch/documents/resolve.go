@@ -0,0 +1,409 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Resolve-doc specific flags
+const (
+	flagWinner         = "winner"
+	flagStrategy       = "strategy"
+	flagTimestampField = "timestamp-field"
+	flagEdit           = "edit"
+	flagDBWide         = "db-wide"
+	flagConflictsView  = "view"
+)
+
+// Resolution strategies
+const (
+	strategyLatestTimestamp = "latest-timestamp"
+	strategyField           = "field="
+)
+
+const defaultTimestampField = "updated_at"
+
+func init() {
+	registry.Register([]string{"resolve"}, resolveDocCmd)
+}
+
+func resolveDocCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "document [target]",
+		Aliases: []string{"doc"},
+		Short:   "Resolves conflicts in a document.",
+		Long: "Fetches the conflicting leaf revisions of a document, chooses a winner, then deletes the losing leaves in a single _bulk_docs request.\n\n" +
+			"The winner is chosen by one of:\n\n" +
+			"  --" + flagWinner + "={rev}                 The leaf with the given revision.\n" +
+			"  --" + flagStrategy + "=" + strategyLatestTimestamp + "    The leaf with the latest RFC 3339 timestamp in the field named by --" + flagTimestampField + ".\n" +
+			"  --" + flagStrategy + "=" + strategyField + "{name}        The leaf with the greatest value of the named field.\n" +
+			"  --" + flagEdit + "                        A merged version, edited in $EDITOR, starting from the current winner. The merged version is stored as a new revision of the current winner.\n\n" +
+			"With --" + flagDBWide + ", every conflicted document in the database is resolved. Conflicted documents are found with _all_docs, " +
+			"or with the view named by --" + flagConflictsView + ", which should emit a row for each conflicted document.\n\n" +
+			"The results of the _bulk_docs requests are output as a single array.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		RunE: resolveDocumentCmd,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	f.String(flagWinner, "", "The revision of the winning leaf.")
+	f.String(flagStrategy, "", "The rule by which to choose the winning leaf: "+strategyLatestTimestamp+", or "+strategyField+"{name}.")
+	f.String(flagTimestampField, defaultTimestampField, "The field used by the "+strategyLatestTimestamp+" strategy.")
+	f.Bool(flagEdit, false, "Edit a merged version in $EDITOR.")
+	f.Bool(flagDBWide, false, "Resolve all conflicted documents in the database.")
+	f.String(flagConflictsView, "", "With --"+flagDBWide+", the view, in the format {ddoc}/{view}, which lists the conflicted documents.")
+	return cmd
+}
+
+func resolveDocumentCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	flags := cmd.Flags()
+	dbWide, err := flags.GetBool(flagDBWide)
+	if err != nil {
+		return err
+	}
+	scope := kouch.TargetDocument
+	if dbWide {
+		scope = kouch.TargetDatabase
+	}
+	o, err := util.CommonOptions(ctx, scope, flags)
+	if err != nil {
+		return err
+	}
+	if dbWide {
+		err = validateDatabase(o.Target)
+	} else {
+		err = validateTarget(o.Target)
+	}
+	if err != nil {
+		return err
+	}
+	pick, err := winnerPicker(flags, dbWide)
+	if err != nil {
+		return err
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	ids := []string{o.Document}
+	if dbWide {
+		if ids, err = conflictedIDs(ctx, c, o, flags); err != nil {
+			return err
+		}
+	}
+	results := []map[string]interface{}{}
+	for _, id := range ids {
+		res, err := resolveConflicts(ctx, c, o, id, pick)
+		if err != nil {
+			if len(results) > 0 {
+				// Report the documents already resolved, before giving up.
+				if e := util.OutputValue(ctx, results); e != nil {
+					return e
+				}
+			}
+			return err
+		}
+		results = append(results, res...)
+	}
+	if e := util.OutputValue(ctx, results); e != nil {
+		return e
+	}
+	var failed int
+	for _, res := range results {
+		if _, ok := res["error"]; ok {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.NewExitError(kouch.ExitBulkErrors, "%d of %d updates failed", failed, len(results))
+	}
+	return nil
+}
+
+// winnerFunc chooses the winner from the leaves of document id, the first of
+// which is the current winner. If the returned document is a new revision to
+// be stored, write is true. A nil document skips the document.
+type winnerFunc func(id string, leaves []map[string]interface{}) (doc map[string]interface{}, write bool, err error)
+
+func winnerPicker(flags *pflag.FlagSet, dbWide bool) (winnerFunc, error) {
+	winner, err := flags.GetString(flagWinner)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := flags.GetString(flagStrategy)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := flags.GetBool(flagEdit)
+	if err != nil {
+		return nil, err
+	}
+	var chosen int
+	for _, set := range []bool{winner != "", strategy != "", edit} {
+		if set {
+			chosen++
+		}
+	}
+	switch {
+	case chosen == 0:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Must choose a winner with --%s, --%s or --%s", flagWinner, flagStrategy, flagEdit)
+	case chosen > 1:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Only one of --%s, --%s and --%s may be used", flagWinner, flagStrategy, flagEdit)
+	case winner != "" && dbWide:
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be used with --%s", flagWinner, flagDBWide)
+	case winner != "":
+		return func(id string, leaves []map[string]interface{}) (map[string]interface{}, bool, error) {
+			for _, leaf := range leaves {
+				if leaf["_rev"] == winner {
+					return leaf, false, nil
+				}
+			}
+			return nil, false, errors.NewExitError(chttp.ExitFailedToInitialize, "Revision %s is not a conflicting leaf of document '%s'", winner, id)
+		}, nil
+	case edit:
+		format, err := flags.GetString(kouch.FlagOutputFormat)
+		if err != nil {
+			return nil, err
+		}
+		return (&docEditor{yaml: format == "yaml"}).merge, nil
+	case strategy == strategyLatestTimestamp:
+		field, err := flags.GetString(flagTimestampField)
+		if err != nil {
+			return nil, err
+		}
+		return fieldPicker(field, func(v interface{}) (interface{}, bool) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			return t, err == nil
+		}), nil
+	case strings.HasPrefix(strategy, strategyField) && len(strategy) > len(strategyField):
+		return fieldPicker(strings.TrimPrefix(strategy, strategyField), func(v interface{}) (interface{}, bool) {
+			switch t := v.(type) {
+			case int64:
+				return float64(t), true
+			case float64, string:
+				return t, true
+			}
+			return nil, false
+		}), nil
+	}
+	return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s'", flagStrategy, strategy)
+}
+
+// fieldPicker returns a winnerFunc which chooses the leaf with the greatest
+// value of field, as converted by value. Leaves without a valid value never
+// win, and ties are won by the current winner.
+func fieldPicker(field string, value func(interface{}) (interface{}, bool)) winnerFunc {
+	return func(id string, leaves []map[string]interface{}) (map[string]interface{}, bool, error) {
+		var best map[string]interface{}
+		var bestValue interface{}
+		for _, leaf := range leaves {
+			v, ok := value(leaf[field])
+			if !ok {
+				continue
+			}
+			if best == nil || greater(v, bestValue) {
+				best, bestValue = leaf, v
+			}
+		}
+		if best == nil {
+			return nil, false, errors.NewExitError(chttp.ExitFailedToInitialize, "No leaf of document '%s' has a valid '%s' field", id, field)
+		}
+		return best, false, nil
+	}
+}
+
+// greater compares values of the same type. Values of differing types are
+// ordered numbers before strings.
+func greater(a, b interface{}) bool {
+	switch x := a.(type) {
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.After(y)
+	case float64:
+		y, ok := b.(float64)
+		return ok && x > y
+	case string:
+		y, ok := b.(string)
+		return !ok || x > y
+	}
+	return false
+}
+
+// merge opens the current winner in the editor, with the conflicting leaves
+// shown for reference, and returns the merged document.
+func (e *docEditor) merge(id string, leaves []map[string]interface{}) (map[string]interface{}, bool, error) {
+	original, err := e.render(leaves[0])
+	if err != nil {
+		return nil, false, err
+	}
+	buf := bytes.NewBuffer(header(fmt.Sprintf("Merge the conflicting revisions of '%s' below. The conflicting revisions are shown at the end of this file.\n"+
+		"Save the file empty to skip this document.", id)))
+	buf.Write(original)
+	conflicts := &bytes.Buffer{}
+	for _, leaf := range leaves[1:] {
+		rendered, err := e.render(leaf)
+		if err != nil {
+			return nil, false, err
+		}
+		fmt.Fprintf(conflicts, "\nConflicting revision %s:\n%s", leaf["_rev"], rendered) // nolint: errcheck
+	}
+	buf.Write(footer(conflicts.String()))
+	content := buf.Bytes()
+	for {
+		edited, err := e.edit(content)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(bytes.TrimSpace(stripComments(edited))) == 0 {
+			fmt.Fprintf(os.Stderr, "Merge of '%s' cancelled, no changes made.\n", id) // nolint: errcheck
+			return nil, false, nil
+		}
+		doc, err := e.parse(edited)
+		if err != nil {
+			content = append(header("Parsing failed: "+err.Error()), stripComments(edited)...)
+			continue
+		}
+		doc["_id"], doc["_rev"] = leaves[0]["_id"], leaves[0]["_rev"]
+		return doc, true, nil
+	}
+}
+
+// fetchLeaves fetches the current winner of document id, with conflicts=true,
+// followed by its conflicting leaves, with open_revs.
+func fetchLeaves(ctx context.Context, c *chttp.Client, o *kouch.Options, id string) ([]map[string]interface{}, error) {
+	doc := *o
+	doc.Target = &kouch.Target{Database: o.Database, Document: id}
+	opts := *o.Options
+	opts.Query = url.Values{"conflicts": []string{"true"}}
+	winner, err := fetchJSON(ctx, c, util.DocPath(&doc), &opts)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := winner.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewExitError(chttp.ExitWeirdReply, "document is not an object")
+	}
+	conflicts, _ := current["_conflicts"].([]interface{})
+	delete(current, "_conflicts")
+	leaves := []map[string]interface{}{current}
+	if len(conflicts) == 0 {
+		return leaves, nil
+	}
+	revs, _ := json.Marshal(conflicts)
+	opts.Query = url.Values{"open_revs": []string{string(revs)}}
+	res, err := fetchJSON(ctx, c, util.DocPath(&doc), &opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, _ := res.([]interface{})
+	for _, row := range rows {
+		r, _ := row.(map[string]interface{})
+		if leaf, ok := r["ok"].(map[string]interface{}); ok {
+			leaves = append(leaves, leaf)
+		}
+	}
+	return leaves, nil
+}
+
+// resolveConflicts resolves the conflicts of a single document, returning the
+// results of the _bulk_docs request.
+func resolveConflicts(ctx context.Context, c *chttp.Client, o *kouch.Options, id string, pick winnerFunc) ([]map[string]interface{}, error) {
+	leaves, err := fetchLeaves(ctx, c, o, id)
+	if err != nil || len(leaves) < 2 {
+		return nil, err
+	}
+	winner, write, err := pick(id, leaves)
+	if err != nil || winner == nil {
+		return nil, err
+	}
+	var docs []map[string]interface{}
+	if write {
+		docs = append(docs, winner)
+	}
+	for _, leaf := range leaves {
+		if leaf["_rev"] == winner["_rev"] {
+			continue
+		}
+		docs = append(docs, map[string]interface{}{"_id": id, "_rev": leaf["_rev"], "_deleted": true})
+	}
+	opts := *o.Options
+	opts.Query = nil
+	opts.Body = chttp.EncodeBody(map[string]interface{}{"docs": docs})
+	var results []map[string]interface{}
+	_, err = c.DoJSON(ctx, http.MethodPost, util.DatabasePath(o)+"/_bulk_docs", &opts, &results)
+	return results, err
+}
+
+// conflictedIDs lists the IDs of conflicted documents, by paging through the
+// view named by --view, or through _all_docs.
+func conflictedIDs(ctx context.Context, c *chttp.Client, o *kouch.Options, flags *pflag.FlagSet) ([]string, error) {
+	view, err := flags.GetString(flagConflictsView)
+	if err != nil {
+		return nil, err
+	}
+	v := *o
+	opts := *o.Options
+	v.Options = &opts
+	v.Options.Query = url.Values{
+		"include_docs": []string{"true"},
+		"conflicts":    []string{"true"},
+	}
+	path := util.DatabasePath(o) + "/_all_docs"
+	if view != "" {
+		t, err := kouch.ParseTarget(kouch.TargetView, view)
+		if err != nil {
+			return nil, err
+		}
+		v.Target = &kouch.Target{Database: o.Database, Document: t.Document, View: t.View}
+		v.Options.Query = url.Values{"reduce": []string{"false"}}
+		path = util.ViewPath(&v)
+	}
+	next := util.Pager(ctx, c, path, util.DefaultPageSize, &v)
+	var ids []string
+	seen := map[string]bool{}
+	for {
+		rows, more, err := next()
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range rows {
+			var row struct {
+				ID  string `json:"id"`
+				Doc struct {
+					Conflicts []string `json:"_conflicts"`
+				} `json:"doc"`
+			}
+			if e := json.Unmarshal(raw, &row); e != nil {
+				return nil, errors.WrapExitError(chttp.ExitWeirdReply, e)
+			}
+			if (view != "" || len(row.Doc.Conflicts) > 0) && !seen[row.ID] {
+				seen[row.ID] = true
+				ids = append(ids, row.ID)
+			}
+		}
+		if !more {
+			return ids, nil
+		}
+	}
+}
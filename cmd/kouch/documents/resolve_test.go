@@ -0,0 +1,229 @@
+package documents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/resolve"
+)
+
+// resolveServer serves a database containing the conflicted documents bar and
+// qux, and the unconflicted document baz. qux is listed only by the partial
+// view. _bulk_docs requests are checked against
+// expected, and answered with response.
+func resolveServer(t *testing.T, expected, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/foo/bar" && q.Get("conflicts") == "true":
+			_, _ = w.Write([]byte(`{"_id":"bar","_rev":"2-bbb","n":1,"updated_at":"2020-01-01T00:00:00Z","_conflicts":["2-aaa"]}`))
+		case r.URL.Path == "/foo/bar" && q.Get("open_revs") == `["2-aaa"]`:
+			_, _ = w.Write([]byte(`[{"ok":{"_id":"bar","_rev":"2-aaa","n":5,"updated_at":"2021-01-01T00:00:00Z"}}]`))
+		case r.URL.Path == "/foo/baz" && q.Get("conflicts") == "true":
+			_, _ = w.Write([]byte(`{"_id":"baz","_rev":"1-xxx"}`))
+		case r.URL.Path == "/foo/_all_docs":
+			if q.Get("include_docs") != "true" || q.Get("conflicts") != "true" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"rows":[{"id":"bar","doc":{"_id":"bar","_conflicts":["2-aaa"]}},{"id":"baz","doc":{"_id":"baz"}}]}`))
+		case r.URL.Path == "/foo/qux" && q.Get("conflicts") == "true":
+			_, _ = w.Write([]byte(`{"_id":"qux","_rev":"2-bbb","_conflicts":["2-aaa"]}`))
+		case r.URL.Path == "/foo/qux" && q.Get("open_revs") == `["2-aaa"]`:
+			_, _ = w.Write([]byte(`[{"ok":{"_id":"qux","_rev":"2-aaa"}}]`))
+		case r.URL.Path == "/foo/_design/conflicts/_view/all":
+			if q.Get("reduce") != "false" || q.Get("limit") != "1001" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"rows":[{"id":"bar","key":"bar"},{"id":"bar","key":"bar"}]}`))
+		case r.URL.Path == "/foo/_design/conflicts/_view/partial":
+			_, _ = w.Write([]byte(`{"rows":[{"id":"bar","key":"bar"},{"id":"qux","key":"qux"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/foo/_bulk_docs":
+			body, _ := ioutil.ReadAll(r.Body)
+			if d := diff.JSON([]byte(expected), body); d != nil {
+				t.Errorf("Unexpected _bulk_docs request:\n%s", d)
+			}
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(response))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(500)
+		}
+	}))
+}
+
+func TestResolveDocCmd(t *testing.T) {
+	const deleteB = `{"docs":[{"_id":"bar","_rev":"2-bbb","_deleted":true}]}`
+	const deleted = `[{"id":"bar","ok":true,"rev":"3-ccc"}]`
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--" + flagWinner, "1-xxx"},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("no winner", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar"},
+		Err:    "Must choose a winner with --winner, --strategy or --edit",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("two winners", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar", "--" + flagWinner, "1-xxx", "--" + flagEdit},
+		Err:    "Only one of --winner, --strategy and --edit may be used",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("winner db-wide", test.CmdTest{
+		Args:   []string{"http://foo.com/foo", "--" + flagWinner, "1-xxx", "--" + flagDBWide},
+		Err:    "--winner may not be used with --db-wide",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid strategy", test.CmdTest{
+		Args:   []string{"http://foo.com/foo/bar", "--" + flagStrategy, "field="},
+		Err:    "Invalid --strategy 'field='",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("unknown winner", func(t *testing.T) interface{} {
+		s := resolveServer(t, "", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagWinner, "2-zzz"},
+			Err:    "Revision 2-zzz is not a conflicting leaf of document 'bar'",
+			Status: chttp.ExitFailedToInitialize,
+		}
+	})
+	tests.Add("no conflicts", func(t *testing.T) interface{} {
+		s := resolveServer(t, "", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/baz", "--" + flagWinner, "1-xxx"},
+			Stdout: "[]\n",
+		}
+	})
+	tests.Add("winner", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagWinner, "2-aaa"},
+			Stdout: deleted + "\n",
+		}
+	})
+	tests.Add("latest timestamp", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagStrategy, strategyLatestTimestamp},
+			Stdout: deleted + "\n",
+		}
+	})
+	tests.Add("missing timestamp", func(t *testing.T) interface{} {
+		s := resolveServer(t, "", "")
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagStrategy, strategyLatestTimestamp, "--" + flagTimestampField, "modified"},
+			Err:    "No leaf of document 'bar' has a valid 'modified' field",
+			Status: chttp.ExitFailedToInitialize,
+		}
+	})
+	tests.Add("field", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagStrategy, "field=n"},
+			Stdout: deleted + "\n",
+		}
+	})
+	tests.Add("merge", func(t *testing.T) interface{} {
+		s := resolveServer(t, `{"docs":[
+			{"_id":"bar","_rev":"2-bbb","n":6,"updated_at":"2021-01-01T00:00:00Z"},
+			{"_id":"bar","_rev":"2-aaa","_deleted":true}
+		]}`, `[{"ok":true,"id":"bar","rev":"3-ccc"},{"ok":true,"id":"bar","rev":"3-ddd"}]`)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(fakeEditor(t, func(s string) string {
+			if !strings.Contains(s, "# Conflicting revision 2-aaa:") {
+				t.Errorf("Conflicting revision not shown:\n%s", s)
+			}
+			return `{"n":6,"updated_at":"2021-01-01T00:00:00Z"}`
+		}))
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagEdit},
+			Stdout: `[{"id":"bar","ok":true,"rev":"3-ccc"},{"id":"bar","ok":true,"rev":"3-ddd"}]` + "\n",
+		}
+	})
+	tests.Add("merge cancelled", func(t *testing.T) interface{} {
+		s := resolveServer(t, "", "")
+		tests.Cleanup(s.Close)
+		tests.Cleanup(fakeEditor(t, func(string) string { return "" }))
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagEdit},
+			Stdout: "[]\n",
+			Stderr: "Merge of 'bar' cancelled, no changes made.\n",
+		}
+	})
+	tests.Add("db-wide", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagDBWide, "--" + flagStrategy, "field=n"},
+			Stdout: deleted + "\n",
+		}
+	})
+	tests.Add("db-wide view", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagDBWide, "--" + flagConflictsView, "conflicts/all", "--" + flagStrategy, strategyLatestTimestamp},
+			Stdout: deleted + "\n",
+		}
+	})
+	tests.Add("db-wide partial failure", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, deleted)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagDBWide, "--" + flagConflictsView, "conflicts/partial", "--" + flagStrategy, "field=n"},
+			Stdout: deleted + "\n",
+			Err:    "No leaf of document 'qux' has a valid 'n' field",
+			Status: chttp.ExitFailedToInitialize,
+		}
+	})
+	tests.Add("merge parse error", func(t *testing.T) interface{} {
+		s := resolveServer(t, `{"docs":[
+			{"_id":"bar","_rev":"2-bbb","n":6},
+			{"_id":"bar","_rev":"2-aaa","_deleted":true}
+		]}`, `[{"ok":true,"id":"bar","rev":"3-ccc"},{"ok":true,"id":"bar","rev":"3-ddd"}]`)
+		tests.Cleanup(s.Close)
+		tests.Cleanup(fakeEditor(t,
+			func(s string) string { return strings.Replace(s, `"n": 1`, `"n": `, 1) },
+			func(s string) string { return s },
+			func(s string) string {
+				if n := strings.Count(s, "# Parsing failed"); n != 1 {
+					t.Errorf("Expected one error header, found %d:\n%s", n, s)
+				}
+				return `{"n":6}`
+			},
+		))
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagEdit},
+			Stdout: `[{"id":"bar","ok":true,"rev":"3-ccc"},{"id":"bar","ok":true,"rev":"3-ddd"}]` + "\n",
+		}
+	})
+	tests.Add("bulk errors", func(t *testing.T) interface{} {
+		s := resolveServer(t, deleteB, `[{"id":"bar","error":"conflict","reason":"Document update conflict."}]`)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar", "--" + flagWinner, "2-aaa"},
+			Stdout: `[{"error":"conflict","id":"bar","reason":"Document update conflict."}]` + "\n",
+			Err:    "1 of 1 updates failed",
+			Status: kouch.ExitBulkErrors,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"resolve", "doc"}))
+}
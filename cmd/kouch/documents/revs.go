@@ -0,0 +1,327 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	kio "github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+)
+
+// Revision statuses, as reported by revs_info
+const (
+	revAvailable = "available"
+	revMissing   = "missing"
+	revDeleted   = "deleted"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getRevsCmd)
+}
+
+func getRevsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revs [target] [rev ...]",
+		Short: "Displays the revision tree of a document.",
+		Long: "Fetches the revision tree of a document, showing its leaves, conflicts, deleted branches, and whether the body of each revision is still available, or has been removed by compaction.\n\n" +
+			"Revisions given after the target are checked with _revs_diff, to report which of them are unknown to the server. This is useful to compare the revisions of a document on two replicas.\n\n" +
+			"With --" + kouch.FlagOutputFormat + "=raw, the tree is drawn as text. Other output formats receive the tree as a structured value. " +
+			"With --" + kouch.FlagOutputFormat + "=template, the tree drawn as text is also available to the template as {{.text}}.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDocument),
+		Annotations: map[string]string{kouch.AnnotationExtraArgs: ""},
+		RunE:        getRevsCmdRun,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDocument, "", "The document ID. May be provided with the target in the format {id}.")
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{id}.")
+	return cmd
+}
+
+// revNode is a single revision in a document's revision tree.
+type revNode struct {
+	Rev      string     `json:"rev"`
+	Status   string     `json:"status"`
+	Leaf     bool       `json:"leaf,omitempty"`
+	Deleted  bool       `json:"deleted,omitempty"`
+	Winner   bool       `json:"winner,omitempty"`
+	Children []*revNode `json:"children,omitempty"`
+
+	parent *revNode
+}
+
+type revsDiff struct {
+	Missing           []string `json:"missing,omitempty"`
+	PossibleAncestors []string `json:"possible_ancestors,omitempty"`
+}
+
+// revTree is the structured output of get revs.
+type revTree struct {
+	ID               string     `json:"id"`
+	Winner           string     `json:"winner"`
+	Conflicts        []string   `json:"conflicts,omitempty"`
+	DeletedConflicts []string   `json:"deleted_conflicts,omitempty"`
+	Tree             []*revNode `json:"tree"`
+	RevsDiff         *revsDiff  `json:"revs_diff,omitempty"`
+	Text             string     `json:"text,omitempty"`
+}
+
+func getRevsCmdRun(cmd *cobra.Command, args []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDocument, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	tree, err := getRevTree(ctx, o)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		tree.RevsDiff, err = getRevsDiff(ctx, o, args[1:])
+		if err != nil {
+			return err
+		}
+	}
+	format, err := cmd.Flags().GetString(kouch.FlagOutputFormat)
+	if err != nil {
+		return err
+	}
+	if format == "template" {
+		buf := &bytes.Buffer{}
+		if e := tree.render(buf); e != nil {
+			return e
+		}
+		tree.Text = buf.String()
+	}
+	if _, ok := kouch.Output(ctx).(kio.ValueWriter); ok {
+		return util.OutputValue(ctx, tree)
+	}
+	w := kio.Underlying(kouch.Output(ctx))
+	if err := tree.render(w); err != nil {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type leafDoc struct {
+	Rev       string `json:"_rev"`
+	Deleted   bool   `json:"_deleted"`
+	Revisions struct {
+		Start int      `json:"start"`
+		IDs   []string `json:"ids"`
+	} `json:"_revisions"`
+	RevsInfo []struct {
+		Rev    string `json:"rev"`
+		Status string `json:"status"`
+	} `json:"_revs_info"`
+}
+
+// getRevTree fetches every leaf with open_revs=all, then the revs_info of
+// each leaf, and assembles the revision tree.
+func getRevTree(ctx context.Context, o *kouch.Options) (*revTree, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	opts := *o.Options
+	opts.Query = url.Values{"open_revs": []string{"all"}, "revs": []string{"true"}}
+	var res []struct {
+		OK *leafDoc `json:"ok"`
+	}
+	if _, e := c.DoJSON(ctx, http.MethodGet, util.DocPath(o), &opts, &res); e != nil {
+		return nil, e
+	}
+	var leaves []*leafDoc
+	status := map[string]string{}
+	for _, r := range res {
+		if r.OK == nil {
+			continue
+		}
+		opts := *o.Options
+		opts.Query = url.Values{"rev": []string{r.OK.Rev}, "revs_info": []string{"true"}}
+		var doc leafDoc
+		if _, e := c.DoJSON(ctx, http.MethodGet, util.DocPath(o), &opts, &doc); e != nil {
+			return nil, e
+		}
+		for _, info := range doc.RevsInfo {
+			status[info.Rev] = info.Status
+		}
+		leaves = append(leaves, r.OK)
+	}
+	if len(leaves) == 0 {
+		return nil, errors.NewExitError(kouch.ExitNotFound, "Document '%s' not found", o.Document)
+	}
+	return buildRevTree(o.Document, leaves, status), nil
+}
+
+func buildRevTree(id string, leaves []*leafDoc, status map[string]string) *revTree {
+	nodes := map[string]*revNode{}
+	node := func(rev string) *revNode {
+		n, ok := nodes[rev]
+		if !ok {
+			n = &revNode{Rev: rev, Status: revMissing}
+			if s, ok := status[rev]; ok {
+				n.Status = s
+			}
+			nodes[rev] = n
+		}
+		return n
+	}
+	leafNodes := make([]*revNode, 0, len(leaves))
+	for _, leaf := range leaves {
+		var child *revNode
+		for i, hash := range leaf.Revisions.IDs {
+			n := node(fmt.Sprintf("%d-%s", leaf.Revisions.Start-i, hash))
+			if child != nil && child.parent == nil {
+				child.parent = n
+				n.Children = append(n.Children, child)
+			}
+			child = n
+		}
+		n := node(leaf.Rev)
+		n.Leaf = true
+		n.Deleted = leaf.Deleted
+		leafNodes = append(leafNodes, n)
+	}
+	sort.Slice(leafNodes, func(i, j int) bool {
+		return winsOver(leafNodes[i], leafNodes[j])
+	})
+	tree := &revTree{ID: id}
+	for i, leaf := range leafNodes {
+		switch {
+		case i == 0:
+			leaf.Winner = true
+			tree.Winner = leaf.Rev
+		case leaf.Deleted:
+			tree.DeletedConflicts = append(tree.DeletedConflicts, leaf.Rev)
+		default:
+			tree.Conflicts = append(tree.Conflicts, leaf.Rev)
+		}
+	}
+	for _, n := range nodes {
+		sortRevs(n.Children)
+		if n.parent == nil {
+			tree.Tree = append(tree.Tree, n)
+		}
+	}
+	sortRevs(tree.Tree)
+	return tree
+}
+
+// winsOver returns true if leaf a wins over leaf b, following CouchDB's
+// deterministic algorithm: non-deleted leaves win over deleted ones, then the
+// longest revision history wins, then the highest revision hash.
+func winsOver(a, b *revNode) bool {
+	if a.Deleted != b.Deleted {
+		return !a.Deleted
+	}
+	ga, ha := splitRev(a.Rev)
+	gb, hb := splitRev(b.Rev)
+	if ga != gb {
+		return ga > gb
+	}
+	return ha > hb
+}
+
+func sortRevs(nodes []*revNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		gi, hi := splitRev(nodes[i].Rev)
+		gj, hj := splitRev(nodes[j].Rev)
+		if gi != gj {
+			return gi < gj
+		}
+		return hi < hj
+	})
+}
+
+func splitRev(rev string) (int, string) {
+	parts := strings.SplitN(rev, "-", 2)
+	gen, _ := strconv.Atoi(parts[0])
+	if len(parts) < 2 {
+		return gen, ""
+	}
+	return gen, parts[1]
+}
+
+func getRevsDiff(ctx context.Context, o *kouch.Options, revs []string) (*revsDiff, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	opts := *o.Options
+	opts.Query = nil
+	opts.Body = chttp.EncodeBody(map[string][]string{o.Document: revs})
+	var res map[string]*revsDiff
+	if _, e := c.DoJSON(ctx, http.MethodPost, util.DatabasePath(o)+"/_revs_diff", &opts, &res); e != nil {
+		return nil, e
+	}
+	if diff, ok := res[o.Document]; ok {
+		return diff, nil
+	}
+	return &revsDiff{}, nil
+}
+
+// render draws the tree as text.
+func (t *revTree) render(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, t.ID); err != nil {
+		return err
+	}
+	if err := renderRevs(w, t.Tree, ""); err != nil {
+		return err
+	}
+	if t.RevsDiff == nil {
+		return nil
+	}
+	if len(t.RevsDiff.Missing) == 0 {
+		_, err := fmt.Fprintln(w, "\nAll given revisions are known to the server.")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nUnknown to the server: %s\n", strings.Join(t.RevsDiff.Missing, ", "))
+	return err
+}
+
+func renderRevs(w io.Writer, nodes []*revNode, prefix string) error {
+	for i, n := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, branch, n.label()); err != nil {
+			return err
+		}
+		if err := renderRevs(w, n.Children, prefix+indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *revNode) label() string {
+	label := fmt.Sprintf("%s (%s)", n.Rev, n.Status)
+	switch {
+	case !n.Leaf:
+		return label
+	case n.Winner:
+		return label + " [winner]"
+	case n.Deleted:
+		return label + " [deleted conflict]"
+	}
+	return label + " [conflict]"
+}
@@ -0,0 +1,135 @@
+package documents
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func revsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch {
+		case r.Method == http.MethodGet && q.Get("open_revs") == "all":
+			_, _ = w.Write([]byte(`[
+				{"ok":{"_id":"bar","_rev":"3-ccc","_revisions":{"start":3,"ids":["ccc","bbb","aaa"]}}},
+				{"ok":{"_id":"bar","_rev":"3-ddd","_revisions":{"start":3,"ids":["ddd","bbb","aaa"]}}},
+				{"ok":{"_id":"bar","_rev":"2-xxx","_deleted":true,"_revisions":{"start":2,"ids":["xxx","aaa"]}}}
+			]`))
+		case r.Method == http.MethodGet && q.Get("rev") == "3-ccc":
+			_, _ = w.Write([]byte(`{"_id":"bar","_rev":"3-ccc","_revs_info":[{"rev":"3-ccc","status":"available"},{"rev":"2-bbb","status":"missing"},{"rev":"1-aaa","status":"missing"}]}`))
+		case r.Method == http.MethodGet && q.Get("rev") == "3-ddd":
+			_, _ = w.Write([]byte(`{"_id":"bar","_rev":"3-ddd","_revs_info":[{"rev":"3-ddd","status":"available"},{"rev":"2-bbb","status":"missing"},{"rev":"1-aaa","status":"missing"}]}`))
+		case r.Method == http.MethodGet && q.Get("rev") == "2-xxx":
+			_, _ = w.Write([]byte(`{"_id":"bar","_rev":"2-xxx","_deleted":true,"_revs_info":[{"rev":"2-xxx","status":"deleted"},{"rev":"1-aaa","status":"missing"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/foo/_revs_diff":
+			_, _ = w.Write([]byte(`{"bar":{"missing":["4-eee"],"possible_ancestors":["3-ccc"]}}`))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(500)
+		}
+	}))
+}
+
+func TestGetRevsCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No document ID provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("not found", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/bar"},
+			Err:    "Document 'bar' not found",
+			Status: kouch.ExitNotFound,
+		}
+	})
+	tests.Add("text", func(t *testing.T) interface{} {
+		s := revsServer(t)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo/bar", "-F", "raw"},
+			Stdout: strings.Join([]string{
+				"bar",
+				"└── 1-aaa (missing)",
+				"    ├── 2-bbb (missing)",
+				"    │   ├── 3-ccc (available) [conflict]",
+				"    │   └── 3-ddd (available) [winner]",
+				"    └── 2-xxx (deleted) [deleted conflict]",
+				"",
+			}, "\n"),
+		}
+	})
+	tests.Add("template", func(t *testing.T) interface{} {
+		s := revsServer(t)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo/bar", "4-eee", "-F", "template", "--template", "winner {{.winner}}\n{{.text}}"},
+			Stdout: strings.Join([]string{
+				"winner 3-ddd",
+				"bar",
+				"└── 1-aaa (missing)",
+				"    ├── 2-bbb (missing)",
+				"    │   ├── 3-ccc (available) [conflict]",
+				"    │   └── 3-ddd (available) [winner]",
+				"    └── 2-xxx (deleted) [deleted conflict]",
+				"",
+				"Unknown to the server: 4-eee",
+				"",
+			}, "\n"),
+		}
+	})
+	tests.Add("yaml with revs diff", func(t *testing.T) interface{} {
+		s := revsServer(t)
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo/bar", "4-eee", "-F", "yaml"},
+			Stdout: strings.Join([]string{
+				"conflicts:",
+				"- 3-ccc",
+				"deleted_conflicts:",
+				"- 2-xxx",
+				"id: bar",
+				"revs_diff:",
+				"  missing:",
+				"  - 4-eee",
+				"  possible_ancestors:",
+				"  - 3-ccc",
+				"tree:",
+				"- children:",
+				"  - children:",
+				"    - leaf: true",
+				"      rev: 3-ccc",
+				"      status: available",
+				"    - leaf: true",
+				"      rev: 3-ddd",
+				"      status: available",
+				"      winner: true",
+				"    rev: 2-bbb",
+				"    status: missing",
+				"  - deleted: true",
+				"    leaf: true",
+				"    rev: 2-xxx",
+				"    status: deleted",
+				"  rev: 1-aaa",
+				"  status: missing",
+				"winner: 3-ddd",
+			}, "\n"),
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "revs"}))
+}
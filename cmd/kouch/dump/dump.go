@@ -0,0 +1,18 @@
+package dump
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, dumpCmd)
+}
+
+func dumpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dump",
+		Short: "Export a resource to an archive.",
+	}
+}
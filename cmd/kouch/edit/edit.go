@@ -0,0 +1,18 @@
+package edit
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, editCmd)
+}
+
+func editCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Edit a resource in your text editor.",
+	}
+}
@@ -0,0 +1,158 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Changes feed specific flags
+const (
+	flagIncludeDocs = "include-docs"
+	flagFilter      = "filter"
+	flagDocIDs      = "doc-ids"
+	flagSelector    = "selector"
+	flagStyle       = "style"
+)
+
+// Built-in filters
+const (
+	filterDocIDs   = "_doc_ids"
+	filterSelector = "_selector"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getChangesCmd)
+}
+
+func getChangesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changes [target]",
+		Short: "Fetches the changes feed of a database.",
+		Long: "Fetches the changes feed of a database.\n\n" +
+			"With --" + flagFeed + "=" + feedContinuous + ", each change is output, in the selected output format, as soon as it is received. " +
+			"With --" + flagFeed + "=" + feedEventSource + ", the response is output as-is.\n\n" +
+			"With --" + flagCheckpoint + ", the sequence of each change is stored in the named file, once the change has been output. " +
+			"If the file exists, the feed resumes from the stored sequence, and --" + flagSince + " is ignored.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getChangesCmdRun,
+	}
+	f := cmd.Flags()
+	addFeedFlags(f)
+	f.Bool(flagIncludeDocs, false, "Include the associated document with each change.")
+	f.String(flagFilter, "", "The filter function to apply, in the format {ddoc}/{filter}.")
+	f.StringSlice(flagDocIDs, nil, "Only return changes for the specified document IDs. Implies the "+filterDocIDs+" filter.")
+	f.String(flagSelector, "", "Only return changes for documents matching the specified Mango selector, in JSON format. Implies the "+filterSelector+" filter.")
+	f.String(flagStyle, "", "Specifies how many revisions are returned in the changes array. Use 'all_docs' to return all leaf revisions.")
+	f.Int(kouch.FlagLimit, 0, "Limit the number of returned changes. 0 means no limit.")
+	f.String(flagCheckpoint, "", "A file in which to store the last processed sequence, and from which to resume on the next run.")
+	return cmd
+}
+
+func getChangesCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getChangesOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getChanges(ctx, o, cmd.Flags())
+}
+
+func getChangesOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := setFeedOpts(o, flags); e != nil {
+		return nil, e
+	}
+	if e := setCheckpointSince(o, flags); e != nil {
+		return nil, e
+	}
+	if e := o.SetParamBool(flags, flagIncludeDocs); e != nil {
+		return nil, e
+	}
+	for _, flag := range []string{flagFilter, flagStyle} {
+		if e := o.SetParamString(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	if e := o.SetParamInt(flags, kouch.FlagLimit); e != nil {
+		return nil, e
+	}
+	body, err := filterBody(flags)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return o, nil
+	}
+	if o.Query().Get(flagFilter) != "" {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be combined with --%s or --%s", flagFilter, flagDocIDs, flagSelector)
+	}
+	filter := filterDocIDs
+	if _, ok := body[flagSelector]; ok {
+		filter = filterSelector
+	}
+	o.Query().Set(flagFilter, filter)
+	o.Options.Body = chttp.EncodeBody(body)
+	return o, nil
+}
+
+// filterBody returns the request body for the built-in filters, or nil if
+// neither --doc-ids nor --selector was provided.
+func filterBody(flags *pflag.FlagSet) (map[string]interface{}, error) {
+	docIDs, err := flags.GetStringSlice(flagDocIDs)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := flags.GetString(flagSelector)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(docIDs) > 0 && selector != "":
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Only one of --%s and --%s may be provided", flagDocIDs, flagSelector)
+	case len(docIDs) > 0:
+		return map[string]interface{}{"doc_ids": docIDs}, nil
+	case selector != "":
+		if !json.Valid([]byte(selector)) {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be valid JSON", flagSelector)
+		}
+		return map[string]interface{}{flagSelector: json.RawMessage(selector)}, nil
+	}
+	return nil, nil
+}
+
+func getChanges(ctx context.Context, o *kouch.Options, flags *pflag.FlagSet) error {
+	if o.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	checkpoint, err := flags.GetString(flagCheckpoint)
+	if err != nil {
+		return err
+	}
+	method := http.MethodGet
+	if o.Options.Body != nil {
+		method = http.MethodPost
+	}
+	if checkpoint != "" {
+		if feedType(o) == feedEventSource {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be used with --%s=%s", flagCheckpoint, flagFeed, feedEventSource)
+		}
+		return getChangesCheckpointed(ctx, method, o, checkpoint)
+	}
+	if feedType(o) == feedEventSource {
+		ctx = kouch.SetOutput(ctx, io.Underlying(kouch.Output(ctx)))
+	}
+	return util.ChttpDo(ctx, method, util.DatabasePath(o)+"/_changes", o)
+}
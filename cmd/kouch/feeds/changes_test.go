@@ -0,0 +1,167 @@
+package feeds
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetChangesOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}{
+		{
+			name: "defaults",
+			args: []string{"foo"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Database: "foo"},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "continuous",
+			args: []string{"--" + flagFeed, feedContinuous, "--" + flagSince, "now", "--" + flagHeartbeat, "1000", "--" + flagIncludeDocs, "--" + flagStyle, "all_docs", "foo"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "foo"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"feed":         []string{"continuous"},
+						"since":        []string{"now"},
+						"heartbeat":    []string{"1000"},
+						"include_docs": []string{"true"},
+						"style":        []string{"all_docs"},
+					},
+				},
+			},
+		},
+		{
+			name:   "invalid feed",
+			args:   []string{"--" + flagFeed, "chunked", "foo"},
+			err:    "Invalid --feed 'chunked'",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name:   "doc ids and selector",
+			args:   []string{"--" + flagDocIDs, "a,b", "--" + flagSelector, "{}", "foo"},
+			err:    "Only one of --doc-ids and --selector may be provided",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name:   "doc ids and filter",
+			args:   []string{"--" + flagDocIDs, "a,b", "--" + flagFilter, "ddoc/filter", "foo"},
+			err:    "--filter may not be combined with --doc-ids or --selector",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name:   "invalid selector",
+			args:   []string{"--" + flagSelector, "{", "foo"},
+			err:    "--selector must be valid JSON",
+			status: chttp.ExitFailedToInitialize,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := getChangesCmd()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, &kouch.Config{})
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := getChangesOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestGetChangesCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("normal", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"results":[{"seq":"1-x","id":"a","changes":[{"rev":"1-a"}]}],"last_seq":"1-x","pending":0}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_changes?limit=1", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagLimit, "1"},
+			Stdout: `{"last_seq":"1-x","pending":0,"results":[{"changes":[{"rev":"1-a"}],"id":"a","seq":"1-x"}]}`,
+		}
+	})
+	tests.Add("continuous", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(strings.NewReader(`{"seq":"1-x","id":"a","changes":[{"rev":"1-a"}]}` + "\n\n" +
+				`{"seq":"2-x","id":"b","changes":[{"rev":"1-b"}]}` + "\n" +
+				`{"last_seq":"2-x","pending":0}` + "\n")),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_changes?feed=continuous", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagFeed, feedContinuous, "-F", "yaml"},
+			Stdout: "changes:\n- rev: 1-a\nid: a\nseq: 1-x\n---\n" +
+				"changes:\n- rev: 1-b\nid: b\nseq: 2-x\n---\n" +
+				"last_seq: 2-x\npending: 0",
+		}
+	})
+	tests.Add("doc ids", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"results":[],"last_seq":"0","pending":0}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if filter := r.URL.Query().Get("filter"); filter != filterDocIDs {
+				t.Errorf("Unexpected filter: %s", filter)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"doc_ids":["a","b"]}`), body); d != nil {
+				t.Error(d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagDocIDs, "a,b"},
+			Stdout: `{"last_seq":"0","pending":0,"results":[]}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "changes"}))
+}
@@ -0,0 +1,109 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/pflag"
+)
+
+const flagCheckpoint = "checkpoint"
+
+// setCheckpointSince sets the since parameter from the checkpoint file named
+// by --checkpoint, if the file exists.
+func setCheckpointSince(o *kouch.Options, flags *pflag.FlagSet) error {
+	filename, err := flags.GetString(flagCheckpoint)
+	if err != nil || filename == "" {
+		return err
+	}
+	seq, err := readCheckpoint(filename)
+	if err != nil || seq == "" {
+		return err
+	}
+	o.Query().Set(flagSince, seq)
+	return nil
+}
+
+// readCheckpoint returns the sequence stored in the checkpoint file, or "" if
+// the file does not exist yet.
+func readCheckpoint(filename string) (string, error) {
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WrapExitError(chttp.ExitReadError, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// writeCheckpoint stores seq in the checkpoint file. The file is replaced
+// atomically, so that an interrupted write never leaves a corrupt checkpoint.
+func writeCheckpoint(filename, seq string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename))
+	if err != nil {
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	_, err = tmp.Write([]byte(seq + "\n"))
+	if e := tmp.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WrapExitError(chttp.ExitWriteError, err)
+	}
+	return nil
+}
+
+// changeSeq returns the sequence of a change, or of the end of the feed, or ""
+// if change contains neither. Sequences are numbers in CouchDB 1.x, and
+// opaque strings since 2.0.
+func changeSeq(change json.RawMessage) string {
+	var seqs struct {
+		Seq     json.RawMessage `json:"seq"`
+		LastSeq json.RawMessage `json:"last_seq"`
+	}
+	if err := json.Unmarshal(change, &seqs); err != nil {
+		return ""
+	}
+	seq := seqs.LastSeq
+	if seq == nil {
+		seq = seqs.Seq
+	}
+	var str string
+	if err := json.Unmarshal(seq, &str); err == nil {
+		return str
+	}
+	return string(seq)
+}
+
+// getChangesCheckpointed consumes the changes feed, writing each change to the
+// output, then storing its sequence in the checkpoint file.
+func getChangesCheckpointed(ctx context.Context, method string, o *kouch.Options, filename string) error {
+	output := kouch.Output(ctx)
+	return util.ChttpStream(ctx, method, util.DatabasePath(o)+"/_changes", o, func(change json.RawMessage) error {
+		var value interface{}
+		if err := json.Unmarshal(change, &value); err != nil {
+			return errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		if err := io.WriteValue(output, value); err != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, err)
+		}
+		if seq := changeSeq(change); seq != "" {
+			return writeCheckpoint(filename, seq)
+		}
+		return nil
+	})
+}
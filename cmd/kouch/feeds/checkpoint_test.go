@@ -0,0 +1,115 @@
+package feeds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestChangeSeq(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   string
+		expected string
+	}{
+		{name: "string seq", change: `{"seq":"2-abc","id":"a"}`, expected: "2-abc"},
+		{name: "numeric seq", change: `{"seq":12345678,"id":"a"}`, expected: "12345678"},
+		{name: "last seq", change: `{"results":[{"seq":"1-x"}],"last_seq":"3-xyz"}`, expected: "3-xyz"},
+		{name: "no seq", change: `{}`, expected: ""},
+		{name: "not an object", change: `[]`, expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if seq := changeSeq(json.RawMessage(test.change)); seq != test.expected {
+				t.Errorf("Unexpected seq: %s", seq)
+			}
+		})
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kouch-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	filename := filepath.Join(dir, "seq")
+	seq, err := readCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != "" {
+		t.Errorf("Expected no checkpoint, got %s", seq)
+	}
+	if e := writeCheckpoint(filename, "1-abc"); e != nil {
+		t.Fatal(e)
+	}
+	if e := writeCheckpoint(filename, "2-abc"); e != nil {
+		t.Fatal(e)
+	}
+	seq, err = readCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != "2-abc" {
+		t.Errorf("Unexpected checkpoint: %s", seq)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected only the checkpoint file, found %d files", len(files))
+	}
+}
+
+func TestGetChangesCheckpointCmd(t *testing.T) {
+	t.Run("resume", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "kouch-checkpoint")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir) // nolint: errcheck
+		filename := filepath.Join(dir, "seq")
+		if e := ioutil.WriteFile(filename, []byte("1-x\n"), 0644); e != nil {
+			t.Fatal(e)
+		}
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(strings.NewReader(`{"seq":"2-x","id":"b","changes":[{"rev":"1-b"}]}` + "\n" +
+				`{"seq":"3-x","id":"c","changes":[{"rev":"1-c"}]}` + "\n")),
+		}, func(t *testing.T, r *http.Request) {
+			if since := r.URL.Query().Get("since"); since != "1-x" {
+				t.Errorf("Unexpected since: %s", since)
+			}
+		})
+		defer s.Close()
+		test.ValidateCmdTest([]string{"get", "changes"})(t, test.CmdTest{
+			Args: []string{s.URL + "/foo", "--" + flagFeed, feedContinuous, "--" + flagSince, "0", "--" + flagCheckpoint, filename},
+			Stdout: `{"changes":[{"rev":"1-b"}],"id":"b","seq":"2-x"}` + "\n" +
+				`{"changes":[{"rev":"1-c"}],"id":"c","seq":"3-x"}`,
+		})
+		seq, err := readCheckpoint(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != "3-x" {
+			t.Errorf("Unexpected checkpoint: %s", seq)
+		}
+	})
+	t.Run("eventsource", func(t *testing.T) {
+		test.ValidateCmdTest([]string{"get", "changes"})(t, test.CmdTest{
+			Args:   []string{"http://foo.com/foo", "--" + flagFeed, feedEventSource, "--" + flagCheckpoint, "seq"},
+			Err:    "--checkpoint may not be used with --feed=eventsource",
+			Status: chttp.ExitFailedToInitialize,
+		})
+	})
+}
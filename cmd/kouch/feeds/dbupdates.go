@@ -0,0 +1,194 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DB updates specific flags
+const (
+	flagDBRegex = "db-regex"
+	flagType    = "type"
+)
+
+var updateTypes = []string{"created", "updated", "deleted"}
+
+func init() {
+	registry.Register([]string{"get"}, getDBUpdatesCmd)
+}
+
+func getDBUpdatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db-updates [target]",
+		Short: "Fetches the database updates feed of a server.",
+		Long: "Fetches the feed of database events (creation, update and deletion) for a server.\n\n" +
+			"With --" + flagDBRegex + " or --" + flagType + ", events are filtered by kouch, after they are received. " +
+			"Filtering is not supported with --" + flagFeed + "=" + feedEventSource + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetRoot),
+		RunE: getDBUpdatesCmdRun,
+	}
+	f := cmd.Flags()
+	addFeedFlags(f)
+	f.String(flagDBRegex, "", "Only output events for databases whose name matches the regular expression.")
+	f.StringSlice(flagType, nil, "Only output events of the specified type(s). Any of: "+strings.Join(updateTypes, ", ")+".")
+	return cmd
+}
+
+func getDBUpdatesCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getDBUpdatesOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	filter, err := newUpdateFilter(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getDBUpdates(ctx, o, filter)
+}
+
+func getDBUpdatesOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetRoot, flags)
+	if err != nil {
+		return nil, err
+	}
+	return o, setFeedOpts(o, flags)
+}
+
+// updateFilter selects database events by database name and event type.
+type updateFilter struct {
+	db    *regexp.Regexp
+	types map[string]bool
+}
+
+// newUpdateFilter returns a filter built from the flags, or nil if no filter
+// was requested.
+func newUpdateFilter(flags *pflag.FlagSet) (*updateFilter, error) {
+	expr, err := flags.GetString(flagDBRegex)
+	if err != nil {
+		return nil, err
+	}
+	types, err := flags.GetStringSlice(flagType)
+	if err != nil {
+		return nil, err
+	}
+	if expr == "" && len(types) == 0 {
+		return nil, nil
+	}
+	f := &updateFilter{}
+	if expr != "" {
+		f.db, err = regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s: %s", flagDBRegex, err)
+		}
+	}
+	if len(types) > 0 {
+		f.types = make(map[string]bool, len(types))
+	}
+	for _, t := range types {
+		if !validUpdateType(t) {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s'", flagType, t)
+		}
+		f.types[t] = true
+	}
+	return f, nil
+}
+
+func validUpdateType(t string) bool {
+	for _, valid := range updateTypes {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+type dbUpdate struct {
+	DBName string `json:"db_name"`
+	Type   string `json:"type"`
+}
+
+func (f *updateFilter) match(u dbUpdate) bool {
+	if f.db != nil && !f.db.MatchString(u.DBName) {
+		return false
+	}
+	if f.types != nil && !f.types[u.Type] {
+		return false
+	}
+	return true
+}
+
+// apply filters a value read from the feed. In normal and longpoll mode, the
+// value contains all the events in its results array. In continuous mode,
+// each value is a single event, or the final last_seq. The returned value is
+// nil if nothing should be output.
+func (f *updateFilter) apply(value json.RawMessage) (interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	}
+	if _, ok := fields["db_name"]; ok {
+		var u dbUpdate
+		if err := json.Unmarshal(value, &u); err != nil {
+			return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		if !f.match(u) {
+			return nil, nil
+		}
+	} else if results, ok := fields["results"]; ok {
+		var updates []json.RawMessage
+		if err := json.Unmarshal(results, &updates); err != nil {
+			return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+		}
+		matched := make([]json.RawMessage, 0, len(updates))
+		for _, update := range updates {
+			var u dbUpdate
+			if err := json.Unmarshal(update, &u); err != nil {
+				return nil, errors.WrapExitError(chttp.ExitWeirdReply, err)
+			}
+			if f.match(u) {
+				matched = append(matched, update)
+			}
+		}
+		fields["results"], _ = json.Marshal(matched)
+		value, _ = json.Marshal(fields)
+	}
+	var result interface{}
+	err := json.Unmarshal(value, &result)
+	return result, err
+}
+
+func getDBUpdates(ctx context.Context, o *kouch.Options, filter *updateFilter) error {
+	if o.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	if filter == nil {
+		if feedType(o) == feedEventSource {
+			ctx = kouch.SetOutput(ctx, io.Underlying(kouch.Output(ctx)))
+		}
+		return util.ChttpDo(ctx, http.MethodGet, "/_db_updates", o)
+	}
+	if feedType(o) == feedEventSource {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s and --%s may not be used with --%s=%s", flagDBRegex, flagType, flagFeed, feedEventSource)
+	}
+	output := kouch.Output(ctx)
+	return util.ChttpStream(ctx, http.MethodGet, "/_db_updates", o, func(value json.RawMessage) error {
+		result, err := filter.apply(value)
+		if err != nil || result == nil {
+			return err
+		}
+		return errors.WrapExitError(chttp.ExitWriteError, io.WriteValue(output, result))
+	})
+}
@@ -0,0 +1,78 @@
+package feeds
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetDBUpdatesCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("invalid regex", test.CmdTest{
+		Args:   []string{"http://foo.com/", "--" + flagDBRegex, "["},
+		Err:    "Invalid --db-regex: error parsing regexp: missing closing ]: `[`",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid type", test.CmdTest{
+		Args:   []string{"http://foo.com/", "--" + flagType, "renamed"},
+		Err:    "Invalid --type 'renamed'",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("filter with eventsource", test.CmdTest{
+		Args:   []string{"http://foo.com/", "--" + flagType, "created", "--" + flagFeed, feedEventSource},
+		Err:    "--db-regex and --type may not be used with --feed=eventsource",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("unfiltered", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"results":[{"db_name":"foo","type":"created","seq":"1-x"}],"last_seq":"1-x"}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/_db_updates?since=now", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL, "--" + flagSince, "now"},
+			Stdout: `{"last_seq":"1-x","results":[{"db_name":"foo","seq":"1-x","type":"created"}]}`,
+		}
+	})
+	tests.Add("filtered normal", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(strings.NewReader(`{"results":[` +
+				`{"db_name":"tenant-a","type":"created","seq":"1-x"},` +
+				`{"db_name":"tenant-a","type":"updated","seq":"2-x"},` +
+				`{"db_name":"_users","type":"created","seq":"3-x"}` +
+				`],"last_seq":"3-x"}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL, "--" + flagDBRegex, "^tenant-", "--" + flagType, "created"},
+			Stdout: `{"last_seq":"3-x","results":[{"db_name":"tenant-a","seq":"1-x","type":"created"}]}`,
+		}
+	})
+	tests.Add("filtered continuous", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Body: ioutil.NopCloser(strings.NewReader(`{"db_name":"tenant-a","type":"created","seq":"1-x"}` + "\n" +
+				`{"db_name":"tenant-a","type":"deleted","seq":"2-x"}` + "\n" +
+				`{"db_name":"tenant-b","type":"created","seq":"3-x"}` + "\n")),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL, "--" + flagFeed, feedContinuous, "--" + flagType, "created"},
+			Stdout: `{"db_name":"tenant-a","seq":"1-x","type":"created"}` + "\n" +
+				`{"db_name":"tenant-b","seq":"3-x","type":"created"}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "db-updates"}))
+}
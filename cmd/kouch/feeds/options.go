@@ -0,0 +1,63 @@
+package feeds
+
+import (
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/spf13/pflag"
+)
+
+// Flags common to the changes and db updates feeds
+const (
+	flagFeed      = "feed"
+	flagSince     = "since"
+	flagHeartbeat = "heartbeat"
+	flagTimeout   = "timeout"
+)
+
+// Supported feed types
+const (
+	feedNormal      = "normal"
+	feedLongpoll    = "longpoll"
+	feedContinuous  = "continuous"
+	feedEventSource = "eventsource"
+)
+
+func addFeedFlags(flags *pflag.FlagSet) {
+	flags.String(flagFeed, feedNormal, "The feed type. One of: "+feedNormal+", "+feedLongpoll+", "+feedContinuous+", "+feedEventSource+".")
+	flags.String(flagSince, "", "Start the results from the change immediately after the given update sequence. Use 'now' to receive only new changes.")
+	flags.Int(flagHeartbeat, 0, "Period in milliseconds after which an empty line is sent, to keep the connection alive, in longpoll or continuous mode.")
+	flags.Int(flagTimeout, 0, "Maximum period in milliseconds to wait for a change before the response is sent, in longpoll or continuous mode.")
+}
+
+// setFeedOpts sets the query parameters common to all feeds on o.
+func setFeedOpts(o *kouch.Options, flags *pflag.FlagSet) error {
+	feed, err := flags.GetString(flagFeed)
+	if err != nil {
+		return err
+	}
+	switch feed {
+	case feedNormal, feedLongpoll, feedContinuous, feedEventSource:
+	default:
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s'", flagFeed, feed)
+	}
+	for _, flag := range []string{flagFeed, flagSince} {
+		if e := o.SetParamString(flags, flag); e != nil {
+			return e
+		}
+	}
+	for _, flag := range []string{flagHeartbeat, flagTimeout} {
+		if e := o.SetParamInt(flags, flag); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// feedType returns the feed type requested in o.
+func feedType(o *kouch.Options) string {
+	if feed := o.Query().Get(flagFeed); feed != "" {
+		return feed
+	}
+	return feedNormal
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getListCmd)
+}
+
+func getListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [target]",
+		Short: "Renders a view with a list function.",
+		Long: "Renders a view with a list function. The response is output as-is.\n\n" +
+			"View query options, such as key or limit, may be passed with --" + flagParam + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetList),
+		RunE: getListCmdRun,
+	}
+	addCommonFlags(cmd.Flags())
+	return cmd
+}
+
+func getListCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getListOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getList(ctx, o)
+}
+
+func getListOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetList, flags)
+	if err != nil {
+		return nil, err
+	}
+	return o, setParams(o, flags)
+}
+
+func getList(ctx context.Context, o *kouch.Options) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	if o.View == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No view name provided")
+	}
+	ctx = kouch.SetOutput(ctx, io.Underlying(kouch.Output(ctx)))
+	return util.ChttpDo(ctx, http.MethodGet, util.ListPath(o), o)
+}
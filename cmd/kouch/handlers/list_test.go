@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestGetListCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"foo/bar/baz"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/csv"}},
+			Body:       ioutil.NopCloser(strings.NewReader("a,1\nb,2\n")),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_design/bar/_list/baz/other/qux?limit=2", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_list/baz/other/qux", "--" + flagParam, "limit=2"},
+			Stdout: "a,1\nb,2\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "list"}))
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagParam       = "param"
+	flagContentType = "content-type"
+)
+
+func addCommonFlags(flags *pflag.FlagSet) {
+	flags.String(kouch.FlagDatabase, "", "The database. May be provided with the target.")
+	flags.StringArray(flagParam, nil, "A query parameter to pass to the function, in the format 'name=value'. May be repeated.")
+}
+
+// setParams adds the query parameters provided with --param to o.
+func setParams(o *kouch.Options, flags *pflag.FlagSet) error {
+	params, err := flags.GetStringArray(flagParam)
+	if err != nil {
+		return err
+	}
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s '%s', expected 'name=value'", flagParam, param)
+		}
+		o.Query().Add(parts[0], parts[1])
+	}
+	return nil
+}
+
+func validateTarget(t *kouch.Target) error {
+	if t.Function == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No function name provided")
+	}
+	if t.Document == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No design document provided")
+	}
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
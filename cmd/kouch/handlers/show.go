@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getShowCmd)
+}
+
+func getShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [target]",
+		Short: "Renders a document with a show function.",
+		Long: "Renders a document with a show function. The response is output as-is.\n\n" +
+			kouch.TargetHelpText(kouch.TargetShow),
+		RunE: getShowCmdRun,
+	}
+	addCommonFlags(cmd.Flags())
+	return cmd
+}
+
+func getShowCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getShowOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getShow(ctx, o)
+}
+
+func getShowOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetShow, flags)
+	if err != nil {
+		return nil, err
+	}
+	return o, setParams(o, flags)
+}
+
+func getShow(ctx context.Context, o *kouch.Options) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	ctx = kouch.SetOutput(ctx, io.Underlying(kouch.Output(ctx)))
+	return util.ChttpDo(ctx, http.MethodGet, util.ShowPath(o), o)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/post"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetShowOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}{
+		{
+			name: "short form",
+			args: []string{"foo/bar/baz"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Database: "foo", Document: "_design/bar", Function: "baz"},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "params",
+			args: []string{"--" + flagParam, "format=html", "--" + flagParam, "q=a=b", "foo/bar/baz"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "foo", Document: "_design/bar", Function: "baz"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"format": []string{"html"},
+						"q":      []string{"a=b"},
+					},
+				},
+			},
+		},
+		{
+			name:   "invalid param",
+			args:   []string{"--" + flagParam, "format", "foo/bar/baz"},
+			err:    "Invalid --param 'format', expected 'name=value'",
+			status: chttp.ExitFailedToInitialize,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := getShowCmd()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, &kouch.Config{})
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := getShowOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestGetShowCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No function name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`<h1>Hello</h1>`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/foo/_design/bar/_show/baz/qux?format=html", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_show/baz/qux", "--" + flagParam, "format=html"},
+			Stdout: `<h1>Hello</h1>`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "show"}))
+}
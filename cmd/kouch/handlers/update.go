@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/go-kivik/kouch/io"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"post"}, postUpdateCmd)
+}
+
+func postUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update [target]",
+		Short: "Calls an update function.",
+		Long: "Calls an update function, with the supplied content as the request body. The response is output as-is.\n\n" +
+			kouch.TargetHelpText(kouch.TargetUpdate),
+		RunE: postUpdateCmdRun,
+	}
+	addCommonFlags(cmd.Flags())
+	cmd.Flags().String(flagContentType, "", "The content type of the request body.")
+	return cmd
+}
+
+func postUpdateCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := postUpdateOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return postUpdate(ctx, o)
+}
+
+func postUpdateOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetUpdate, flags)
+	if err != nil {
+		return nil, err
+	}
+	if e := setParams(o, flags); e != nil {
+		return nil, e
+	}
+	o.Options.Body = kouch.Input(ctx)
+	o.Options.ContentType, err = flags.GetString(flagContentType)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func postUpdate(ctx context.Context, o *kouch.Options) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	ctx = kouch.SetOutput(ctx, io.Underlying(kouch.Output(ctx)))
+	return util.ChttpDo(ctx, http.MethodPost, util.UpdatePath(o), o)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestPostUpdateCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"-d", "foo"},
+		Err:    "No function name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 201,
+			Body:       ioutil.NopCloser(strings.NewReader("Updated qux")),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/foo/_design/bar/_update/baz/qux" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "text/plain" {
+				t.Errorf("Unexpected Content-Type: %s", ct)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != "new value" {
+				t.Errorf("Unexpected body: %s", string(body))
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo/_design/bar/_update/baz/qux", "-d", "new value", "--" + flagContentType, "text/plain"},
+			Stdout: "Updated qux",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"post", "update"}))
+}
@@ -8,17 +8,31 @@ import (
 	_ "github.com/go-kivik/kouch/cmd/kouch/root"
 
 	// Top-level sub-commands
+	_ "github.com/go-kivik/kouch/cmd/kouch/copy"
 	_ "github.com/go-kivik/kouch/cmd/kouch/create"
 	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/dump"
+	_ "github.com/go-kivik/kouch/cmd/kouch/edit"
 	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/patch"
+	_ "github.com/go-kivik/kouch/cmd/kouch/post"
+	_ "github.com/go-kivik/kouch/cmd/kouch/purge"
 	_ "github.com/go-kivik/kouch/cmd/kouch/put"
+	_ "github.com/go-kivik/kouch/cmd/kouch/resolve"
+	_ "github.com/go-kivik/kouch/cmd/kouch/restore"
 
 	// The individual sub-commands
 	_ "github.com/go-kivik/kouch/cmd/kouch/attachments"
+	_ "github.com/go-kivik/kouch/cmd/kouch/bulk"
 	_ "github.com/go-kivik/kouch/cmd/kouch/config"
 	_ "github.com/go-kivik/kouch/cmd/kouch/database"
 	_ "github.com/go-kivik/kouch/cmd/kouch/documents"
+	_ "github.com/go-kivik/kouch/cmd/kouch/feeds"
+	_ "github.com/go-kivik/kouch/cmd/kouch/handlers"
+	_ "github.com/go-kivik/kouch/cmd/kouch/mango"
+	_ "github.com/go-kivik/kouch/cmd/kouch/replication"
 	_ "github.com/go-kivik/kouch/cmd/kouch/uuids"
+	_ "github.com/go-kivik/kouch/cmd/kouch/views"
 )
 
 func main() {
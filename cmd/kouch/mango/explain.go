@@ -0,0 +1,109 @@
+package mango
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registry.Register(nil, explainCmd)
+}
+
+func explainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain [target]",
+		Short: "Shows which index a Mango query would use.",
+		Long: "Shows which index a Mango query would use, and the key range it would scan. The query is built the same way as for the find command.\n\n" +
+			"With the yaml or template output formats, a summary is rendered in place of the raw response. The summary contains the fields " +
+			"'index' (a description of the chosen index), 'fields' (the fields in the index), 'range', 'selector', 'limit', and 'skip'.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: explainCmdRun,
+	}
+	addQueryFlags(cmd.Flags())
+	return cmd
+}
+
+func explainCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	query, err := findQuery(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	o.Options.Body = chttp.EncodeBody(query)
+	path := util.DatabasePath(o) + "/_explain"
+	format, err := cmd.Flags().GetString(kouch.FlagOutputFormat)
+	if err != nil {
+		return err
+	}
+	if format != "yaml" && format != "template" {
+		return util.ChttpDo(ctx, http.MethodPost, path, o)
+	}
+	return explainSummary(ctx, path, o)
+}
+
+type explanation struct {
+	Index struct {
+		Ddoc *string `json:"ddoc"`
+		Name string  `json:"name"`
+		Type string  `json:"type"`
+		Def  struct {
+			Fields []interface{} `json:"fields"`
+		} `json:"def"`
+	} `json:"index"`
+	Selector interface{} `json:"selector"`
+	Range    interface{} `json:"range"`
+	Limit    int         `json:"limit"`
+	Skip     int         `json:"skip"`
+}
+
+type summary struct {
+	Index    string        `json:"index"`
+	Fields   []interface{} `json:"fields"`
+	Range    interface{}   `json:"range"`
+	Selector interface{}   `json:"selector"`
+	Limit    int           `json:"limit"`
+	Skip     int           `json:"skip"`
+}
+
+// explainSummary fetches the explanation, and writes a summary of it to the
+// output.
+func explainSummary(ctx context.Context, path string, o *kouch.Options) error {
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	var e explanation
+	if _, err := c.DoJSON(ctx, http.MethodPost, path, o.Options, &e); err != nil {
+		return err
+	}
+	return util.OutputValue(ctx, summarize(&e))
+}
+
+func summarize(e *explanation) *summary {
+	index := e.Index.Name
+	if e.Index.Ddoc != nil {
+		index = *e.Index.Ddoc + "/" + index
+	}
+	return &summary{
+		Index:    fmt.Sprintf("%s (%s)", index, e.Index.Type),
+		Fields:   e.Index.Def.Fields,
+		Range:    e.Range,
+		Selector: e.Selector,
+		Limit:    e.Limit,
+		Skip:     e.Skip,
+	}
+}
@@ -0,0 +1,74 @@
+package mango
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestExplainCmd(t *testing.T) {
+	const response = `{"dbname":"db","index":{"ddoc":"_design/foo","name":"by-year","type":"json","def":{"fields":[{"year":"asc"}]}},` +
+		`"selector":{"year":{"$gt":2010}},"opts":{},"limit":25,"skip":0,"fields":"all_fields","range":{"start_key":[2010],"end_key":[{}]}}`
+	tests := testy.NewTable()
+	tests.Add("raw json", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"index":{"name":"_all_docs"}}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/db/_explain" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + flagSelector, "{}"},
+			Stdout: `{"index":{"name":"_all_docs"}}`,
+		}
+	})
+	tests.Add("yaml summary", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args: []string{s.URL + "/db", "--" + flagSelector, "year: {$gt: 2010}", "-F", "yaml"},
+			Stdout: "fields:\n- year: asc\nindex: _design/foo/by-year (json)\nlimit: 25\n" +
+				"range:\n  end_key:\n  - {}\n  start_key:\n  - 2010\nselector:\n  year:\n    $gt: 2010\nskip: 0",
+		}
+	})
+	tests.Add("template", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + flagSelector, "{}", "-F", "template", "--template", "{{.index}}"},
+			Stdout: "_design/foo/by-year (json)",
+		}
+	})
+	tests.Add("special index", func(t *testing.T) interface{} {
+		s := testy.ServeResponse(&http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"index":{"ddoc":null,"name":"_all_docs","type":"special","def":{"fields":[{"_id":"asc"}]}}}`)),
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + flagSelector, "{}", "-F", "template", "--template", "{{.index}}"},
+			Stdout: "_all_docs (special)",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"explain"}))
+}
@@ -0,0 +1,168 @@
+package mango
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register(nil, findCmd)
+}
+
+func findCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "find [target]",
+		Short: "Finds documents using a Mango query.",
+		Long: "Finds documents using a declarative Mango query.\n\n" +
+			"The query may be provided in full with --" + kouch.FlagData + ", --" + kouch.FlagDataJSON + ", or --" + kouch.FlagDataYAML + ", " +
+			"or built from the --" + flagSelector + " and related options. When both are used, the options take precedence. " +
+			"If neither is provided, the query is read from stdin.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: findDocsCmd,
+	}
+	addQueryFlags(cmd.Flags())
+	cmd.Flags().Bool(kouch.FlagAll, false, "Follow bookmarks until all matching documents are fetched, and output one document per line as newline-delimited JSON.")
+	return cmd
+}
+
+// addQueryFlags adds the flags used to build a Mango query.
+func addQueryFlags(f *pflag.FlagSet) {
+	f.String(flagSelector, "", "The selector, in JSON or YAML format.")
+	f.StringSlice(flagFields, nil, "The fields to return for each document.")
+	f.StringSlice(flagSort, nil, "The fields by which to sort. Append ':desc' to a field name for descending order.")
+	f.Int(kouch.FlagLimit, 0, "Maximum number of results returned. 0 means the server default.")
+	f.Int(kouch.FlagSkip, 0, "Skip the first 'n' results.")
+	f.String(flagUseIndex, "", "The index to use, in the format {ddoc} or {ddoc}/{name}.")
+	f.String(flagBookmark, "", "A bookmark, as returned by a previous query, from which to resume.")
+	f.Bool(flagExecutionStats, false, "Include execution statistics in the response.")
+}
+
+func findDocsCmd(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	query, err := findQuery(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	all, err := cmd.Flags().GetBool(kouch.FlagAll)
+	if err != nil {
+		return err
+	}
+	if all {
+		return findAll(ctx, o, query)
+	}
+	o.Options.Body = chttp.EncodeBody(query)
+	return util.ChttpDo(ctx, http.MethodPost, util.DatabasePath(o)+"/_find", o)
+}
+
+// findQuery builds the query from the input, if provided, and the command
+// line options.
+func findQuery(ctx context.Context, flags *pflag.FlagSet) (map[string]interface{}, error) {
+	selector, err := flags.GetString(flagSelector)
+	if err != nil {
+		return nil, err
+	}
+	// Without a selector option, the query must come from the input.
+	query, err := readInput(ctx, flags, selector == "")
+	if err != nil {
+		return nil, err
+	}
+	if selector != "" {
+		if query["selector"], err = parseYAML(flagSelector, selector); err != nil {
+			return nil, err
+		}
+	}
+	if fields, _ := flags.GetStringSlice(flagFields); len(fields) > 0 {
+		query["fields"] = fields
+	}
+	if sort, _ := flags.GetStringSlice(flagSort); len(sort) > 0 {
+		query["sort"] = sortSpec(sort)
+	}
+	for _, flag := range []string{kouch.FlagLimit, kouch.FlagSkip} {
+		if flags.Changed(flag) {
+			query[flag], _ = flags.GetInt(flag)
+		}
+	}
+	if index, _ := flags.GetString(flagUseIndex); index != "" {
+		query["use_index"] = useIndex(index)
+	}
+	if bookmark, _ := flags.GetString(flagBookmark); bookmark != "" {
+		query["bookmark"] = bookmark
+	}
+	if stats, _ := flags.GetBool(flagExecutionStats); stats {
+		query["execution_stats"] = true
+	}
+	if _, ok := query["selector"]; !ok {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No selector provided")
+	}
+	return query, nil
+}
+
+// sortSpec converts fields in the format 'field' or 'field:desc' to a Mango
+// sort specification.
+func sortSpec(fields []string) []interface{} {
+	spec := make([]interface{}, len(fields))
+	for i, field := range fields {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) == 1 {
+			spec[i] = field
+			continue
+		}
+		spec[i] = map[string]string{parts[0]: parts[1]}
+	}
+	return spec
+}
+
+// useIndex converts an index in the format {ddoc} or {ddoc}/{name} to the
+// value expected by CouchDB.
+func useIndex(index string) interface{} {
+	ddoc := strings.TrimPrefix(index, "_design/")
+	parts := strings.SplitN(ddoc, "/", 2)
+	if len(parts) == 1 {
+		return ddoc
+	}
+	return parts
+}
+
+type findPage struct {
+	Docs     []json.RawMessage `json:"docs"`
+	Bookmark string            `json:"bookmark"`
+}
+
+// findAll repeats the query, following the returned bookmark, until no more
+// documents are returned.
+func findAll(ctx context.Context, o *kouch.Options, query map[string]interface{}) error {
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	path := util.DatabasePath(o) + "/_find"
+	return util.StreamPages(ctx, func() ([]json.RawMessage, bool, error) {
+		opts := *o.Options
+		opts.Body = chttp.EncodeBody(query)
+		page := findPage{}
+		if _, err := c.DoJSON(ctx, http.MethodPost, path, &opts, &page); err != nil {
+			return nil, false, err
+		}
+		more := len(page.Docs) > 0 && page.Bookmark != "" && page.Bookmark != query["bookmark"]
+		query["bookmark"] = page.Bookmark
+		delete(query, "skip")
+		return page.Docs, more, nil
+	})
+}
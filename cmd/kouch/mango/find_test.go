@@ -0,0 +1,163 @@
+package mango
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/create"
+	_ "github.com/go-kivik/kouch/cmd/kouch/delete"
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestFindQuery(t *testing.T) {
+	type fqTest struct {
+		args     []string
+		input    string
+		expected interface{}
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("no selector", fqTest{
+		args:   []string{"--" + kouch.FlagData, "{}"},
+		input:  `{}`,
+		err:    "No selector provided",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("invalid input", fqTest{
+		args:   []string{"--" + kouch.FlagData, "x"},
+		input:  `x`,
+		err:    "invalid character 'x' looking for beginning of value",
+		status: chttp.ExitPostError,
+	})
+	tests.Add("yaml selector", fqTest{
+		args: []string{"--" + flagSelector, "type: user\nage: {$gt: 21}"},
+		expected: map[string]interface{}{
+			"selector": map[string]interface{}{
+				"type": "user",
+				"age":  map[string]interface{}{"$gt": 21},
+			},
+		},
+	})
+	tests.Add("invalid selector", fqTest{
+		args:   []string{"--" + flagSelector, "{"},
+		err:    "Invalid --selector: yaml: line 1: did not find expected node content",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("input with overrides", fqTest{
+		args: []string{
+			"--" + kouch.FlagData, "x",
+			"--" + flagFields, "_id,name",
+			"--" + flagSort, "name,age:desc",
+			"--" + kouch.FlagLimit, "10",
+			"--" + kouch.FlagSkip, "0",
+			"--" + flagUseIndex, "_design/foo/bar",
+			"--" + flagBookmark, "xyz",
+			"--" + flagExecutionStats,
+		},
+		input: `{"selector":{"type":"user"},"limit":5}`,
+		expected: map[string]interface{}{
+			"selector":        map[string]interface{}{"type": "user"},
+			"fields":          []string{"_id", "name"},
+			"sort":            []interface{}{"name", map[string]string{"age": "desc"}},
+			"limit":           10,
+			"skip":            0,
+			"use_index":       []string{"foo", "bar"},
+			"bookmark":        "xyz",
+			"execution_stats": true,
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, test fqTest) {
+		cmd := findCmd()
+		cmd.Flags().String(kouch.FlagData, "", "")
+		if err := cmd.ParseFlags(test.args); err != nil {
+			t.Fatal(err)
+		}
+		ctx := kouch.SetInput(kouch.GetContext(cmd), ioutil.NopCloser(strings.NewReader(test.input)))
+		query, err := findQuery(ctx, cmd.Flags())
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if test.expected == nil {
+			return
+		}
+		if d := diff.Interface(test.expected, query); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestFindCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"--" + flagSelector, "{}"},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("yaml data", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"docs":[{"_id":"a"}],"bookmark":"xyz"}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/foo/_find" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"selector":{"type":"user"}}`), body); d != nil {
+				t.Errorf("Unexpected body:\n%s", d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + kouch.FlagDataYAML, "selector:\n  type: user"},
+			Stdout: `{"bookmark":"xyz","docs":[{"_id":"a"}]}`,
+		}
+	})
+	tests.Add("all", func(t *testing.T) interface{} {
+		var requests int
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			switch requests {
+			case 1:
+				if d := diff.JSON([]byte(`{"selector":{"type":"user"},"limit":2}`), body); d != nil {
+					t.Errorf("Unexpected first body:\n%s", d)
+				}
+				_, _ = w.Write([]byte(`{"docs":[{"_id":"a"},{"_id":"b"}],"bookmark":"one"}`))
+			case 2:
+				if d := diff.JSON([]byte(`{"selector":{"type":"user"},"limit":2,"bookmark":"one"}`), body); d != nil {
+					t.Errorf("Unexpected second body:\n%s", d)
+				}
+				_, _ = w.Write([]byte(`{"docs":[{"_id":"c"}],"bookmark":"two"}`))
+			default:
+				_, _ = w.Write([]byte(`{"docs":[],"bookmark":"two"}`))
+			}
+		}))
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/foo", "--" + flagSelector, "type: user", "--" + kouch.FlagLimit, "2", "--" + kouch.FlagAll},
+			Stdout: "{\"_id\":\"a\"}\n{\"_id\":\"b\"}\n{\"_id\":\"c\"}\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"find"}))
+}
@@ -0,0 +1,187 @@
+package mango
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"create"}, createIndexCmd)
+	registry.Register([]string{"get"}, getIndexesCmd)
+	registry.Register([]string{"delete"}, deleteIndexCmd)
+}
+
+func createIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index [target]",
+		Short: "Creates a Mango index.",
+		Long: "Creates a Mango index.\n\n" +
+			"The index definition may be provided in full with --" + kouch.FlagData + ", --" + kouch.FlagDataJSON + ", or --" + kouch.FlagDataYAML + ", " +
+			"or built from the --" + flagFields + " and related options. When both are used, the options take precedence.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: createIndexCmdRun,
+	}
+	f := cmd.Flags()
+	f.StringSlice(flagFields, nil, "The fields to index. Append ':desc' to a field name for descending order.")
+	f.String(flagDdoc, "", "The design document in which to create the index. By default, a new design document is created.")
+	f.String(flagName, "", "The name of the index. By default, a name is generated.")
+	f.String(flagType, "", "The index type: 'json' or 'text'. Defaults to 'json'.")
+	f.String(flagPartialFilter, "", "A selector, in JSON or YAML format, to limit the documents included in the index.")
+	return cmd
+}
+
+func createIndexCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	body, err := indexDef(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	o.Options.Body = chttp.EncodeBody(body)
+	return util.ChttpDo(ctx, http.MethodPost, util.DatabasePath(o)+"/_index", o)
+}
+
+// indexDef builds the index definition from the input, if provided, and the
+// command line options.
+func indexDef(ctx context.Context, flags *pflag.FlagSet) (map[string]interface{}, error) {
+	fields, err := flags.GetStringSlice(flagFields)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readInput(ctx, flags, len(fields) == 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		index, _ := body["index"].(map[string]interface{})
+		if index == nil {
+			index = map[string]interface{}{}
+		}
+		index["fields"] = sortSpec(fields)
+		body["index"] = index
+	}
+	if filter, _ := flags.GetString(flagPartialFilter); filter != "" {
+		index, _ := body["index"].(map[string]interface{})
+		if index == nil {
+			return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No index fields provided")
+		}
+		if index["partial_filter_selector"], err = parseYAML(flagPartialFilter, filter); err != nil {
+			return nil, err
+		}
+	}
+	for _, flag := range []string{flagDdoc, flagName, flagType} {
+		if v, _ := flags.GetString(flag); v != "" {
+			body[flag] = v
+		}
+	}
+	if _, ok := body["index"]; !ok {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "No index fields provided")
+	}
+	return body, nil
+}
+
+func getIndexesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "indexes [target]",
+		Aliases: []string{"index", "idx"},
+		Short:   "Lists the Mango indexes in a database.",
+		Long: "Lists the Mango indexes in a database.\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: getIndexesCmdRun,
+	}
+	cmd.Flags().Int(kouch.FlagLimit, 0, "Limit the number of indexes returned. 0 means no limit.")
+	cmd.Flags().Int(kouch.FlagSkip, 0, "Skip this number of indexes before returning results.")
+	return cmd
+}
+
+func getIndexesCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	for _, flag := range []string{kouch.FlagLimit, kouch.FlagSkip} {
+		if e := o.SetParamInt(cmd.Flags(), flag); e != nil {
+			return e
+		}
+	}
+	return util.ChttpDo(ctx, http.MethodGet, util.DatabasePath(o)+"/_index", o)
+}
+
+func deleteIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index [target]",
+		Short: "Deletes a Mango index.",
+		Long: "Deletes a Mango index, identified by --" + flagDdoc + " and --" + flagName + ".\n\n" +
+			kouch.TargetHelpText(kouch.TargetDatabase),
+		RunE: deleteIndexCmdRun,
+	}
+	cmd.Flags().String(flagDdoc, "", "The design document containing the index.")
+	cmd.Flags().String(flagName, "", "The name of the index.")
+	cmd.Flags().String(flagType, "json", "The index type: 'json' or 'text'.")
+	return cmd
+}
+
+func deleteIndexCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := util.CommonOptions(ctx, kouch.TargetDatabase, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if e := validateTarget(o.Target); e != nil {
+		return e
+	}
+	path, err := indexPath(o, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return util.ChttpDo(ctx, http.MethodDelete, path, o)
+}
+
+// indexPath returns the path to the index identified by the flags.
+func indexPath(o *kouch.Options, flags *pflag.FlagSet) (string, error) {
+	ddoc, err := flags.GetString(flagDdoc)
+	if err != nil {
+		return "", err
+	}
+	name, err := flags.GetString(flagName)
+	if err != nil {
+		return "", err
+	}
+	if ddoc == "" || name == "" {
+		return "", errors.NewExitError(chttp.ExitFailedToInitialize, "Must provide --%s and --%s", flagDdoc, flagName)
+	}
+	indexType, err := flags.GetString(flagType)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/_index/%s/%s/%s", util.DatabasePath(o), chttp.EncodeDocID(ddocID(ddoc)),
+		url.PathEscape(indexType), url.PathEscape(name)), nil
+}
+
+// ddocID adds the _design/ prefix to ddoc, if it is missing.
+func ddocID(ddoc string) string {
+	if len(ddoc) > 8 && ddoc[:8] == "_design/" {
+		return ddoc
+	}
+	return "_design/" + ddoc
+}
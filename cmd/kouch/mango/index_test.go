@@ -0,0 +1,156 @@
+package mango
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+)
+
+func TestIndexDef(t *testing.T) {
+	type idTest struct {
+		args     []string
+		input    string
+		expected interface{}
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("no fields", idTest{
+		args:   []string{"--" + kouch.FlagData, "{}"},
+		input:  `{}`,
+		err:    "No index fields provided",
+		status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("options only", idTest{
+		args: []string{
+			"--" + flagFields, "name,age:desc",
+			"--" + flagDdoc, "foo",
+			"--" + flagName, "bar",
+			"--" + flagPartialFilter, "type: user",
+		},
+		expected: map[string]interface{}{
+			"index": map[string]interface{}{
+				"fields":                  []interface{}{"name", map[string]string{"age": "desc"}},
+				"partial_filter_selector": map[string]interface{}{"type": "user"},
+			},
+			"ddoc": "foo",
+			"name": "bar",
+		},
+	})
+	tests.Add("input with overrides", idTest{
+		args:  []string{"--" + kouch.FlagData, "x", "--" + flagType, "text"},
+		input: `{"index":{"fields":["name"]},"type":"json"}`,
+		expected: map[string]interface{}{
+			"index": map[string]interface{}{"fields": []interface{}{"name"}},
+			"type":  "text",
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, test idTest) {
+		cmd := createIndexCmd()
+		cmd.Flags().String(kouch.FlagData, "", "")
+		if err := cmd.ParseFlags(test.args); err != nil {
+			t.Fatal(err)
+		}
+		ctx := kouch.SetInput(kouch.GetContext(cmd), ioutil.NopCloser(strings.NewReader(test.input)))
+		def, err := indexDef(ctx, cmd.Flags())
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if test.expected == nil {
+			return
+		}
+		if d := diff.Interface(test.expected, def); d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestCreateIndexCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"result":"created","id":"_design/foo","name":"bar"}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/db/_index" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"index":{"fields":["name"]},"ddoc":"foo","name":"bar"}`), body); d != nil {
+				t.Errorf("Unexpected body:\n%s", d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + flagFields, "name", "--" + flagDdoc, "foo", "--" + flagName, "bar"},
+			Stdout: `{"id":"_design/foo","name":"bar","result":"created"}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"create", "index"}))
+}
+
+func TestGetIndexesCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{},
+		Err:    "No database name provided",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"total_rows":1,"indexes":[{"ddoc":null,"name":"_all_docs","type":"special"}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/db/_index?limit=5", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + kouch.FlagLimit, "5"},
+			Stdout: `{"indexes":[{"ddoc":null,"name":"_all_docs","type":"special"}],"total_rows":1}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "indexes"}))
+}
+
+func TestDeleteIndexCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("missing name", test.CmdTest{
+		Args:   []string{"http://foo.com/db", "--" + flagDdoc, "foo"},
+		Err:    "Must provide --ddoc and --name",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "DELETE", s.URL+"/db/_index/_design/foo/json/bar", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db", "--" + flagDdoc, "_design/foo", "--" + flagName, "bar"},
+			Stdout: `{"ok":true}`,
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"delete", "index"}))
+}
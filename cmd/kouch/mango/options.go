@@ -0,0 +1,74 @@
+package mango
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/icza/dyno"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Find-specific flags
+const (
+	flagSelector       = "selector"
+	flagFields         = "fields"
+	flagSort           = "sort"
+	flagUseIndex       = "use-index"
+	flagBookmark       = "bookmark"
+	flagExecutionStats = "execution-stats"
+)
+
+// Index-specific flags
+const (
+	flagDdoc          = "ddoc"
+	flagName          = "name"
+	flagType          = "type"
+	flagPartialFilter = "partial-filter"
+)
+
+func validateTarget(t *kouch.Target) error {
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
+
+// hasInput returns true if one of the data options was provided.
+func hasInput(flags *pflag.FlagSet) bool {
+	for _, flag := range []string{kouch.FlagData, kouch.FlagDataJSON, kouch.FlagDataYAML} {
+		if flags.Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// readInput decodes the JSON input into a map, if any data option was
+// provided, or if force is true, in which case the input defaults to stdin.
+func readInput(ctx context.Context, flags *pflag.FlagSet, force bool) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	if !force && !hasInput(flags) {
+		return body, nil
+	}
+	if err := json.NewDecoder(kouch.Input(ctx)).Decode(&body); err != nil {
+		return nil, errors.WrapExitError(chttp.ExitPostError, err)
+	}
+	return body, nil
+}
+
+// parseYAML parses src, which may be in YAML or JSON format, into a data
+// structure suitable for JSON encoding.
+func parseYAML(flagName, src string) (interface{}, error) {
+	var i interface{}
+	if err := yaml.Unmarshal([]byte(src), &i); err != nil {
+		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Invalid --%s: %s", flagName, err)
+	}
+	return dyno.ConvertMapI2MapS(i), nil
+}
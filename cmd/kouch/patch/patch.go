@@ -0,0 +1,18 @@
+package patch
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, patchCmd)
+}
+
+func patchCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "patch",
+		Short: "Apply a patch to a resource.",
+	}
+}
@@ -0,0 +1,18 @@
+package post
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, postCmd)
+}
+
+func postCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "post",
+		Short: "Submit data to a resource.",
+	}
+}
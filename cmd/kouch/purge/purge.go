@@ -0,0 +1,18 @@
+package purge
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, purgeCmd)
+}
+
+func purgeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently remove a resource.",
+	}
+}
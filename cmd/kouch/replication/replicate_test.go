@@ -0,0 +1,174 @@
+package replication
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestReplicateCmd(t *testing.T) {
+	type rt struct {
+		method, path string
+		body         interface{}
+	}
+	serve := func(t *testing.T, req *rt) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req.method, req.path = r.Method, r.URL.Path
+			if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+				t.Fatal(err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}))
+	}
+	conf := func(t *testing.T, dir, root string) string {
+		file := path.Join(dir, "config")
+		content := "default-context: local\ncontexts:\n" +
+			"- name: local\n  context:\n    root: " + root + "\n    user: admin\n    password: abc123\n" +
+			"- name: remote\n  context:\n    root: http://remote.com:5984/\n    user: bob\n    password: xyz\n"
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	const (
+		adminAuth = "Basic YWRtaW46YWJjMTIz"
+		bobAuth   = "Basic Ym9iOnh5eg=="
+	)
+
+	tests := []struct {
+		name     string
+		args     func(root string) []string
+		stdout   string
+		err      string
+		status   int
+		expected *rt
+	}{
+		{
+			name:   "no target",
+			args:   func(_ string) []string { return []string{"foo"} },
+			err:    "Must provide exactly one source and one target",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name:   "undefined context",
+			args:   func(_ string) []string { return []string{"nope:foo", "bar"} },
+			err:    "Context 'nope' not defined",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			name: "contexts",
+			args: func(_ string) []string {
+				return []string{"remote:foo/bar", "local:baz", "--" + flagCreateTarget, "--" + flagContinuous}
+			},
+			stdout: `{"ok":true}`,
+			expected: &rt{
+				method: http.MethodPost,
+				path:   "/_replicate",
+				body: map[string]interface{}{
+					"source": map[string]interface{}{
+						"url":     "http://remote.com:5984/foo%2Fbar",
+						"headers": map[string]interface{}{"Authorization": bobAuth},
+					},
+					"target": map[string]interface{}{
+						"headers": map[string]interface{}{"Authorization": adminAuth},
+					},
+					"create_target": true,
+					"continuous":    true,
+				},
+			},
+		},
+		{
+			name: "url and relative",
+			args: func(root string) []string {
+				return []string{"http://foo.com/src", "tgt", "--" + flagDocIDs, "a,b", "--" + flagSinceSeq, "10"}
+			},
+			stdout: `{"ok":true}`,
+			expected: &rt{
+				method: http.MethodPost,
+				path:   "/_replicate",
+				body: map[string]interface{}{
+					"source": map[string]interface{}{
+						"url": "http://foo.com/src",
+					},
+					"target": map[string]interface{}{
+						"headers": map[string]interface{}{"Authorization": adminAuth},
+					},
+					"doc_ids":   []interface{}{"a", "b"},
+					"since_seq": 10.0,
+				},
+			},
+		},
+		{
+			name: "persistent with selector",
+			args: func(_ string) []string {
+				return []string{"remote:foo", "local:bar", "--" + flagPersistent, "--" + flagSelector, "type: user"}
+			},
+			stdout: `{"ok":true}`,
+			expected: &rt{
+				method: http.MethodPost,
+				path:   "/_replicator",
+				body: map[string]interface{}{
+					"source": map[string]interface{}{
+						"url":     "http://remote.com:5984/foo",
+						"headers": map[string]interface{}{"Authorization": bobAuth},
+					},
+					"target": map[string]interface{}{
+						"headers": map[string]interface{}{"Authorization": adminAuth},
+					},
+					"selector": map[string]interface{}{"type": "user"},
+				},
+			},
+		},
+		{
+			name: "conflicting filters",
+			args: func(_ string) []string {
+				return []string{"remote:foo", "local:bar", "--" + flagFilter, "ddoc/f", "--" + flagDocIDs, "a"}
+			},
+			err:    "Only one of --doc-ids, --selector and --filter may be used",
+			status: chttp.ExitFailedToInitialize,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &rt{}
+			s := serve(t, req)
+			defer s.Close()
+			dir := new(string)
+			defer testy.TempDir(t, dir)()
+			args := append([]string{"--kouchconfig", conf(t, *dir, s.URL)}, tt.args(s.URL)...)
+			test.ValidateCmdTest([]string{"replicate"})(t, test.CmdTest{
+				Args:   args,
+				Stdout: tt.stdout,
+				Err:    tt.err,
+				Status: tt.status,
+			})
+			if tt.expected == nil {
+				return
+			}
+			// The target URL depends on the test server's address.
+			tt.expected.body.(map[string]interface{})["target"].(map[string]interface{})["url"] = s.URL + "/" + targetDB(tt.args(s.URL))
+			if d := diff.Interface(tt.expected, req); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func targetDB(args []string) string {
+	_, db, ok := splitContext(args[1])
+	if !ok {
+		return args[1]
+	}
+	return db
+}
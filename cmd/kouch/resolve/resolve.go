@@ -0,0 +1,18 @@
+package resolve
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+)
+
+func init() {
+	registry.Register(nil, resolveCmd)
+}
+
+func resolveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resolve",
+		Short: "Resolve conflicts in a resource.",
+	}
+}
@@ -47,7 +47,7 @@ func rootCmd(version string) *cobra.Command {
 
 func prerun(cmd *cobra.Command, args []string) error {
 	ctx := kouch.GetContext(cmd)
-	ctx, err := setTarget(ctx, args)
+	ctx, err := setTarget(ctx, cmd, args)
 	if err != nil {
 		return err
 	}
@@ -78,11 +78,11 @@ func prerun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func setTarget(ctx context.Context, args []string) (context.Context, error) {
+func setTarget(ctx context.Context, cmd *cobra.Command, args []string) (context.Context, error) {
 	if len(args) == 0 {
 		return ctx, nil
 	}
-	if len(args) > 1 {
+	if _, ok := cmd.Annotations[kouch.AnnotationExtraArgs]; !ok && len(args) > 1 {
 		return nil, errors.NewExitError(chttp.ExitFailedToInitialize, "Too many targets provided")
 	}
 	return kouch.SetTarget(ctx, args[0]), nil
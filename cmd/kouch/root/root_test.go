@@ -41,6 +41,7 @@ func TestRootCmd(t *testing.T) {
 func TestSetTarget(t *testing.T) {
 	tests := []struct {
 		name     string
+		cmd      *cobra.Command
 		args     []string
 		expected string
 		err      string
@@ -62,12 +63,22 @@ func TestSetTarget(t *testing.T) {
 			args:     []string{"foo"},
 			expected: "foo",
 		},
+		{
+			name:     "extra arguments allowed",
+			cmd:      &cobra.Command{Annotations: map[string]string{kouch.AnnotationExtraArgs: ""}},
+			args:     []string{"foo", "bar"},
+			expected: "foo",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			ctx := context.Background()
 			var err error
-			ctx, err = setTarget(ctx, test.args)
+			cmd := test.cmd
+			if cmd == nil {
+				cmd = &cobra.Command{}
+			}
+			ctx, err = setTarget(ctx, cmd, test.args)
 			testy.ExitStatusError(t, test.err, test.status, err)
 			val := kouch.GetTarget(ctx)
 			if val != test.expected {
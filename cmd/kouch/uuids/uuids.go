@@ -2,8 +2,6 @@ package uuids
 
 import (
 	"net/http"
-	"net/url"
-	"strconv"
 
 	"github.com/spf13/cobra"
 
@@ -39,9 +37,7 @@ func getUUIDsOpts(cmd *cobra.Command, args []string) (*kouch.Options, error) {
 	if err != nil {
 		return nil, err
 	}
-	if count != 1 {
-		o.Options.Query = url.Values{"count": []string{strconv.Itoa(count)}}
-	}
+	util.SetUUIDsCount(o.Options, count)
 	return o, nil
 }
 
@@ -51,5 +47,5 @@ func getUUIDsCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	return util.ChttpDo(ctx, http.MethodGet, "/_uuids", o)
+	return util.ChttpDo(ctx, http.MethodGet, util.UUIDsPath, o)
 }
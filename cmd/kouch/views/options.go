@@ -0,0 +1,34 @@
+package views
+
+import (
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// Get-view specific flags
+const (
+	flagKey         = "key"
+	flagReduce      = "reduce"
+	flagGroup       = "group"
+	flagGroupLevel  = "group-level"
+	flagIncludeDocs = "include-docs"
+	flagStale       = "stale"
+	flagUpdate      = "update"
+)
+
+func validateTarget(t *kouch.Target) error {
+	if t.View == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No view name provided")
+	}
+	if t.Document == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No design document provided")
+	}
+	if t.Database == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No database name provided")
+	}
+	if t.Root == "" {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "No root URL provided")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package views
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/cmd/kouch/registry"
+	"github.com/go-kivik/kouch/internal/errors"
+	"github.com/go-kivik/kouch/internal/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	registry.Register([]string{"get"}, getViewCmd)
+}
+
+func getViewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "view [target]",
+		Short: "Queries a view.",
+		Long: "Queries a view.\n\n" +
+			"Key options (--" + flagKey + ", --" + kouch.FlagKeys + ", --" + kouch.FlagStartKey + ", --" + kouch.FlagEndKey + ") may be provided as JSON. " +
+			"Any value which is not valid JSON is treated as a string, so 'foo' and '\"foo\"' are equivalent, but '123' is a number.\n\n" +
+			kouch.TargetHelpText(kouch.TargetView),
+		RunE: getViewCmdRun,
+	}
+	f := cmd.Flags()
+	f.String(kouch.FlagDatabase, "", "The database. May be provided with the target in the format /{db}/{ddoc}/{view}.")
+	f.String(flagKey, "", "Return only rows that match the specified key.")
+	f.String(kouch.FlagKeys, "", "Return only rows that match the specified keys. Provide a JSON array, or a comma-separated list.")
+	f.String(kouch.FlagStartKey, "", "Return rows starting with the specified key.")
+	f.String(kouch.FlagEndKey, "", "Stop returning rows when the specified key is reached.")
+	f.Bool(flagReduce, true, "Use the reduce function, if the view has one.")
+	f.Bool(flagGroup, false, "Group the results using the reduce function to a group or single row.")
+	f.Int(flagGroupLevel, 0, "Specify the group level to use.")
+	f.Bool(flagIncludeDocs, false, "Include the associated document with each row.")
+	f.String(flagStale, "", "Allow the results from a stale view to be used. Supported values: ok, update_after.")
+	f.String(flagUpdate, "", "Whether the view should be updated before responding. Supported values: true, false, lazy.")
+	f.Int(kouch.FlagLimit, 0, "Limit the number of returned rows. 0 means no limit.")
+	f.Int(kouch.FlagSkip, 0, "Skip this number of rows before returning results.")
+	f.Bool(kouch.FlagDescending, false, "Return the rows in descending order by key.")
+	f.Bool(kouch.FlagAll, false, "Fetch the entire key range, one page at a time, and output one row per line as newline-delimited JSON.")
+	f.Int(kouch.FlagPageSize, util.DefaultPageSize, "The number of rows to fetch per request, with --"+kouch.FlagAll+".")
+	return cmd
+}
+
+func getViewCmdRun(cmd *cobra.Command, _ []string) error {
+	ctx := kouch.GetContext(cmd)
+	o, err := getViewOpts(ctx, cmd.Flags())
+	if err != nil {
+		return err
+	}
+	return getView(ctx, o, cmd.Flags())
+}
+
+func getViewOpts(ctx context.Context, flags *pflag.FlagSet) (*kouch.Options, error) {
+	o, err := util.CommonOptions(ctx, kouch.TargetView, flags)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range []string{flagKey, kouch.FlagStartKey, kouch.FlagEndKey} {
+		if e := o.SetParamJSON(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{flagStale, flagUpdate} {
+		if e := o.SetParamString(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{flagGroupLevel, kouch.FlagLimit, kouch.FlagSkip} {
+		if e := o.SetParamInt(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	for _, flag := range []string{flagReduce, flagGroup, flagIncludeDocs, kouch.FlagDescending} {
+		if e := o.SetParamBool(flags, flag); e != nil {
+			return nil, e
+		}
+	}
+	return o, o.SetKeys(flags, kouch.JSONValue)
+}
+
+func getView(ctx context.Context, o *kouch.Options, flags *pflag.FlagSet) error {
+	if err := validateTarget(o.Target); err != nil {
+		return err
+	}
+	all, err := flags.GetBool(kouch.FlagAll)
+	if err != nil {
+		return err
+	}
+	if all {
+		pageSize, err := flags.GetInt(kouch.FlagPageSize)
+		if err != nil {
+			return err
+		}
+		if o.Options.Body != nil || flags.Changed(kouch.FlagLimit) {
+			return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s may not be combined with --%s or --%s", kouch.FlagAll, kouch.FlagKeys, kouch.FlagLimit)
+		}
+		return util.PageRows(ctx, util.ViewPath(o), pageSize, o)
+	}
+	method := http.MethodGet
+	if o.Options.Body != nil {
+		method = http.MethodPost
+	}
+	return util.ChttpDo(ctx, method, util.ViewPath(o), o)
+}
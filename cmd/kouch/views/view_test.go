@@ -0,0 +1,169 @@
+package views
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/test"
+
+	_ "github.com/go-kivik/kouch/cmd/kouch/get"
+	_ "github.com/go-kivik/kouch/cmd/kouch/root"
+)
+
+func TestGetViewOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected interface{}
+		err      string
+		status   int
+	}{
+		{
+			name: "full url",
+			args: []string{"http://foo.com/db/_design/foo/_view/bar"},
+			expected: &kouch.Options{
+				Target:  &kouch.Target{Root: "http://foo.com", Database: "db", Document: "_design/foo", View: "bar"},
+				Options: &chttp.Options{},
+			},
+		},
+		{
+			name: "keys",
+			args: []string{"--" + flagKey, "abc", "--" + kouch.FlagStartKey, "[1,2]", "--" + kouch.FlagEndKey, "123", "db/foo/bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "db", Document: "_design/foo", View: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"key":       []string{`"abc"`},
+						"start_key": []string{"[1,2]"},
+						"end_key":   []string{"123"},
+					},
+				},
+			},
+		},
+		{
+			name: "reduce and grouping",
+			args: []string{"--" + flagReduce + "=false", "--" + flagGroup, "--" + flagGroupLevel, "2", "db/foo/bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "db", Document: "_design/foo", View: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"reduce":      []string{"false"},
+						"group":       []string{"true"},
+						"group_level": []string{"2"},
+					},
+				},
+			},
+		},
+		{
+			name: "stale and update",
+			args: []string{"--" + flagStale, "ok", "--" + flagUpdate, "lazy", "db/foo/bar"},
+			expected: &kouch.Options{
+				Target: &kouch.Target{Database: "db", Document: "_design/foo", View: "bar"},
+				Options: &chttp.Options{
+					Query: url.Values{
+						"stale":  []string{"ok"},
+						"update": []string{"lazy"},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := getViewCmd()
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatal(err)
+			}
+			ctx := kouch.GetContext(cmd)
+			ctx = kouch.SetConf(ctx, &kouch.Config{})
+			if flags := cmd.Flags().Args(); len(flags) > 0 {
+				ctx = kouch.SetTarget(ctx, flags[0])
+			}
+			kouch.SetContext(ctx, cmd)
+			opts, err := getViewOpts(ctx, cmd.Flags())
+			testy.ExitStatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestGetViewCmd(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("validation fails", test.CmdTest{
+		Args:   []string{"http://foo.com/db/_design/foo"},
+		Err:    "incomplete target URL",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("all with keys", test.CmdTest{
+		Args:   []string{"http://foo.com/db/foo/bar", "--" + kouch.FlagAll, "--" + kouch.FlagKeys, "a,b"},
+		Err:    "--all may not be combined with --keys or --limit",
+		Status: chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"total_rows":1,"offset":0,"rows":[{"id":"a","key":"a","value":1}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/db/_design/foo/_view/bar?include_docs=true", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db/_design/foo/_view/bar", "--" + flagIncludeDocs},
+			Stdout: `{"offset":0,"rows":[{"id":"a","key":"a","value":1}],"total_rows":1}`,
+		}
+	})
+	tests.Add("keys", func(t *testing.T) interface{} {
+		s := testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"rows":[]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Unexpected method: %s", r.Method)
+			}
+			if r.URL.Path != "/db/_design/foo/_view/bar" {
+				t.Errorf("Unexpected path: %s", r.URL.Path)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(`{"keys":["a",1]}`), body); d != nil {
+				t.Error(d)
+			}
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db/foo/bar", "--" + kouch.FlagKeys, "a,1"},
+			Stdout: `{"rows":[]}`,
+		}
+	})
+	tests.Add("all", func(t *testing.T) interface{} {
+		var s *httptest.Server
+		s = testy.ServeResponseValidator(t, &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"total_rows":1,"offset":0,"rows":[{"id":"a","key":"a","value":1}]}`)),
+		}, func(t *testing.T, r *http.Request) {
+			expected := test.NewRequest(t, "GET", s.URL+"/db/_design/foo/_view/bar?limit=11", nil)
+			test.CheckRequest(t, expected, r)
+		})
+		tests.Cleanup(s.Close)
+		return test.CmdTest{
+			Args:   []string{s.URL + "/db/foo/bar", "--" + kouch.FlagAll, "--" + kouch.FlagPageSize, "10"},
+			Stdout: `{"id":"a","key":"a","value":1}` + "\n",
+		}
+	})
+
+	tests.Run(t, test.ValidateCmdTest([]string{"get", "view"}))
+}
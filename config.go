@@ -40,12 +40,20 @@ func (c *Config) DefaultCtx() (*Context, error) {
 	if name == "" {
 		return nil, InitError("No default context")
 	}
+	if ctx, err := c.Context(name); err == nil {
+		return ctx, nil
+	}
+	return nil, InitError(fmt.Sprintf("Default context '%s' not defined", name))
+}
+
+// Context returns the named context.
+func (c *Config) Context(name string) (*Context, error) {
 	for _, nc := range c.Contexts {
 		if nc.Name == name {
 			return nc.Context, nil
 		}
 	}
-	return nil, InitError(fmt.Sprintf("Default context '%s' not defined", name))
+	return nil, InitError(fmt.Sprintf("Context '%s' not defined", name))
 }
 
 // Dump dumps the config as a JSON string on r. Any errors will be returned as
@@ -43,3 +43,38 @@ func TestDefaultCtx(t *testing.T) {
 		})
 	}
 }
+
+func TestContext(t *testing.T) {
+	conf := &Config{
+		Contexts: []NamedContext{
+			{Name: "foo", Context: &Context{Root: "foo.com"}},
+			{Name: "bar", Context: &Context{Root: "bar.com", User: "bob"}},
+		},
+	}
+	tests := []struct {
+		name     string
+		context  string
+		expected *Context
+		err      string
+	}{
+		{
+			name:    "not defined",
+			context: "baz",
+			err:     "Context 'baz' not defined",
+		},
+		{
+			name:     "Success",
+			context:  "bar",
+			expected: &Context{Root: "bar.com", User: "bob"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, err := conf.Context(test.context)
+			testy.Error(t, test.err, err)
+			if d := diff.Interface(test.expected, ctx); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
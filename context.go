@@ -88,6 +88,11 @@ func SetTarget(ctx context.Context, target string) context.Context {
 	return context.WithValue(ctx, targetContextKey, target)
 }
 
+// AnnotationExtraArgs is the cobra.Command annotation which marks a command as
+// accepting further arguments after the target. Without it, passing more than
+// one argument is an error.
+const AnnotationExtraArgs = "kouch:extra-args"
+
 type contexter interface {
 	Context() context.Context
 }
@@ -6,6 +6,21 @@ import (
 
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// Exit statuses for conditions which curl does not distinguish. They are
+// numbered above the range used by curl, to avoid ambiguity.
+const (
+	// ExitConflict indicates that the server rejected an update due to a
+	// document conflict (HTTP status 409).
+	ExitConflict = 100
+	// ExitNotFound indicates that the requested resource does not exist (HTTP
+	// status 404).
+	ExitNotFound = 101
+	// ExitBulkErrors indicates that a bulk operation completed, but the
+	// server rejected one or more of the individual documents.
+	ExitBulkErrors = 102
 )
 
 // InitError returns an error for init failures.
@@ -16,6 +31,16 @@ func (i InitError) Error() string { return string(i) }
 // ExitStatus returns ExitFailedToInitialize
 func (i InitError) ExitStatus() int { return chttp.ExitFailedToInitialize }
 
+// ConflictError converts an HTTP 409 Conflict error to an error with the
+// ExitConflict exit status, and a more helpful message. Any other error is
+// returned unaltered.
+func ConflictError(err error) error {
+	if kivik.StatusCode(err) != kivik.StatusConflict {
+		return err
+	}
+	return errors.NewExitError(ExitConflict, "Document update conflict: the revision is missing or not current. Provide the current revision with --%s, or use --%s.", FlagRev, FlagAutoRev)
+}
+
 type exitStatuser interface {
 	ExitStatus() int
 }
@@ -40,6 +40,36 @@ func TestExit(t *testing.T) {
 	}
 }
 
+func TestConflictError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:   "not a conflict",
+			err:    &httpErr{error: errors.New("foo"), httpStatus: 404, exitStatus: chttp.ExitNotRetrieved},
+			status: chttp.ExitNotRetrieved,
+		},
+		{
+			name:   "conflict",
+			err:    &httpErr{error: errors.New("foo"), httpStatus: 409, exitStatus: chttp.ExitNotRetrieved},
+			status: ExitConflict,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := ExitStatus(ConflictError(test.err))
+			if status != test.status {
+				t.Errorf("Unexpected exit status:\nExpected: %d\n  Actual: %d\n", test.status, status)
+			}
+		})
+	}
+}
+
 type httpErr struct {
 	httpStatus, exitStatus int
 	error
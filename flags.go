@@ -27,6 +27,14 @@ const (
 	FlagShards       = "shards"
 	FlagPassword     = "password"
 	FlagContext      = "context"
+	FlagStartKey     = "start-key"
+	FlagEndKey       = "end-key"
+	FlagLimit        = "limit"
+	FlagSkip         = "skip"
+	FlagDescending   = "descending"
+	FlagKeys         = "keys"
+	FlagPageSize     = "page-size"
+	FlagAll          = "all"
 
 	// Curl-equivalent short flags
 	FlagShortVerbose    = "v"
@@ -41,5 +41,45 @@ Except for _design/ and _local/ documents, any slashes in a database name or doc
   - http://host.com/foo/bar/baz.html -- Full URL
 
   Except for _design/ and _local/ documents, any slashes in a database name, document id, or filename must be URL-encoded.
+`,
+	TargetView: `[target] may be a full or relative URL to the view. Examples:
+
+  - bar/baz                                         -- View 'baz' in the 'bar' design doc in the current database
+  - foo/bar/baz                                     -- View 'baz' in the 'bar' design doc in the 'foo' database
+  - _design/bar/_view/baz                           -- Relative URL to the view in the current database
+  - foo/_design/bar/_view/baz                       -- View 'baz' in the 'bar' design doc in the 'foo' database at the current Root URL
+  - http://localhost:5984/foo/_design/bar/_view/baz -- Full URL
+
+Any slashes in a database name, design document name, or view name must be URL-encoded.
+`,
+	TargetShow: `[target] may be a full or relative URL to the show function. Examples:
+
+  - bar/baz                                             -- Show function 'baz' in the 'bar' design doc in the current database
+  - foo/bar/baz                                         -- Show function 'baz' in the 'bar' design doc in the 'foo' database
+  - foo/_design/bar/_show/baz                           -- Show function 'baz' in the 'bar' design doc in the 'foo' database
+  - foo/_design/bar/_show/baz/qux                       -- Show function 'baz', applied to the document 'qux'
+  - http://localhost:5984/foo/_design/bar/_show/baz/qux -- Full URL
+
+A document ID may only be provided with the full form. Any slashes in a database name, design document name, or function name must be URL-encoded.
+`,
+	TargetList: `[target] may be a full or relative URL to the list function and view. Examples:
+
+  - bar/baz/qux                                         -- List function 'baz' in the 'bar' design doc, applied to the view 'qux' in the same design doc
+  - foo/bar/baz/qux                                     -- As above, in the 'foo' database
+  - foo/_design/bar/_list/baz/qux                       -- As above
+  - foo/_design/bar/_list/baz/other/qux                 -- List function 'baz', applied to the view 'qux' in the 'other' design doc
+  - http://localhost:5984/foo/_design/bar/_list/baz/qux -- Full URL
+
+Any slashes in a database name, design document name, function name, or view name must be URL-encoded.
+`,
+	TargetUpdate: `[target] may be a full or relative URL to the update function. Examples:
+
+  - bar/baz                                               -- Update function 'baz' in the 'bar' design doc in the current database
+  - foo/bar/baz                                           -- Update function 'baz' in the 'bar' design doc in the 'foo' database
+  - foo/_design/bar/_update/baz                           -- Update function 'baz' in the 'bar' design doc in the 'foo' database
+  - foo/_design/bar/_update/baz/qux                       -- Update function 'baz', applied to the document 'qux'
+  - http://localhost:5984/foo/_design/bar/_update/baz/qux -- Full URL
+
+A document ID may only be provided with the full form. Any slashes in a database name, design document name, or function name must be URL-encoded.
 `,
 }
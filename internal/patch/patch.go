@@ -0,0 +1,316 @@
+// Package patch implements JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7396), for values decoded by encoding/json. Numbers are expected to be
+// decoded as json.Number, so that they are preserved exactly.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Merge applies an RFC 7396 merge patch to target, and returns the result.
+// target may be modified.
+func Merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = Merge(t[k], v)
+	}
+	return t
+}
+
+// Operation is a single RFC 6902 JSON Patch operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the RFC 6902 JSON Patch operations to doc, in order, and
+// returns the result. doc may be modified. If any operation fails, the error
+// identifies the failed operation by index.
+func Apply(doc interface{}, ops []Operation) (interface{}, error) {
+	for i, op := range ops {
+		var err error
+		doc, err = apply(doc, op)
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d (%s %s)", i, op.Op, op.Path)
+		}
+	}
+	return doc, nil
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value, err = deepCopy(value)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, errors.New("cannot move a value into itself")
+		}
+		doc, err = remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, errors.Errorf("unknown operation '%s'", op.Op)
+}
+
+// parsePointer parses an RFC 6901 JSON Pointer into its reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, errors.Errorf("invalid JSON pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		var err error
+		if doc, err = child(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func child(node interface{}, token string) (interface{}, error) {
+	switch t := node.(type) {
+	case map[string]interface{}:
+		v, ok := t[token]
+		if !ok {
+			return nil, errors.Errorf("member '%s' not found", token)
+		}
+		return v, nil
+	case []interface{}:
+		i, err := index(token, len(t)-1)
+		if err != nil {
+			return nil, err
+		}
+		return t[i], nil
+	}
+	return nil, errors.Errorf("cannot reference '%s' in a scalar value", token)
+}
+
+// index parses token as an array index, which must not exceed max.
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.Errorf("invalid array index '%s'", token)
+	}
+	if i > max {
+		return 0, errors.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+// mutate calls fn with the parent of the value referenced by path, and the
+// final reference token. fn returns the replacement for the parent, which is
+// necessary when the length of an array changes.
+func mutate(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	c, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	c, err = mutate(c, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch t := doc.(type) {
+	case map[string]interface{}:
+		t[path[0]] = c
+	case []interface{}:
+		i, _ := strconv.Atoi(path[0])
+		t[i] = c
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch t := parent.(type) {
+		case map[string]interface{}:
+			t[token] = value
+			return t, nil
+		case []interface{}:
+			if token == "-" {
+				return append(t, value), nil
+			}
+			i, err := index(token, len(t))
+			if err != nil {
+				return nil, err
+			}
+			t = append(t, nil)
+			copy(t[i+1:], t[i:])
+			t[i] = value
+			return t, nil
+		}
+		return nil, errors.Errorf("cannot add '%s' to a scalar value", token)
+	})
+}
+
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+		switch t := parent.(type) {
+		case map[string]interface{}:
+			delete(t, token)
+			return t, nil
+		case []interface{}:
+			i, _ := strconv.Atoi(token)
+			return append(t[:i], t[i+1:]...), nil
+		}
+		return parent, nil
+	})
+}
+
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+		switch t := parent.(type) {
+		case map[string]interface{}:
+			t[token] = value
+		case []interface{}:
+			i, _ := strconv.Atoi(token)
+			t[i] = value
+		}
+		return parent, nil
+	})
+}
+
+func decode(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// equal reports whether a and b are equal JSON values. Numbers are compared
+// by value, so that 1 and 1.0 are equal.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, _, errX := big.ParseFloat(string(x), 10, 1024, big.ToNearestEven)
+		fy, _, errY := big.ParseFloat(string(y), 10, 1024, big.ToNearestEven)
+		return errX == nil && errY == nil && fx.Cmp(fy) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func deepCopy(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decode(raw)
+}
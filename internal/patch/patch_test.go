@@ -0,0 +1,203 @@
+package patch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		patch    string
+		expected string
+	}{
+		{name: "replace member", target: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "add member", target: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{name: "remove member", target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{name: "replace array", target: `{"a":[1,2]}`, patch: `{"a":[3]}`, expected: `{"a":[3]}`},
+		{name: "nested", target: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":"g"}}`, expected: `{"a":{"b":"c","f":"g"}}`},
+		{name: "scalar target", target: `{"a":"b"}`, patch: `{"a":{"c":null,"d":1}}`, expected: `{"a":{"d":1}}`},
+		{name: "non-object patch", target: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(mustDecode(t, test.target), mustDecode(t, test.patch))
+			if d := diff.JSON([]byte(test.expected), mustEncode(t, result)); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "add member",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			expected: `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:     "add array element",
+			doc:      `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:     "append",
+			doc:      `{"foo":{"bar":[1]}}`,
+			patch:    `[{"op":"add","path":"/foo/bar/-","value":2}]`,
+			expected: `{"foo":{"bar":[1,2]}}`,
+		},
+		{
+			name:     "add null",
+			doc:      `{}`,
+			patch:    `[{"op":"add","path":"/foo","value":null}]`,
+			expected: `{"foo":null}`,
+		},
+		{
+			name:     "remove",
+			doc:      `{"foo":["bar","qux","baz"],"a":1}`,
+			patch:    `[{"op":"remove","path":"/foo/1"},{"op":"remove","path":"/a"}]`,
+			expected: `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:     "replace",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/foo","value":{"a":1}}]`,
+			expected: `{"foo":{"a":1}}`,
+		},
+		{
+			name:     "move",
+			doc:      `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:     "copy",
+			doc:      `{"foo":{"a":1}}`,
+			patch:    `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"replace","path":"/bar/a","value":2}]`,
+			expected: `{"foo":{"a":1},"bar":{"a":2}}`,
+		},
+		{
+			name:     "test passes",
+			doc:      `{"foo":{"a":[1,"x"]}}`,
+			patch:    `[{"op":"test","path":"/foo","value":{"a":[1,"x"]}}]`,
+			expected: `{"foo":{"a":[1,"x"]}}`,
+		},
+		{
+			name:     "escaped pointer",
+			doc:      `{"a/b":1,"m~n":2}`,
+			patch:    `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`,
+			expected: `{"a/b":3}`,
+		},
+		{
+			name:     "test equal numbers",
+			doc:      `{"foo":1,"bar":{"n":[2.5]}}`,
+			patch:    `[{"op":"test","path":"/foo","value":1.0},{"op":"test","path":"/bar","value":{"n":[25e-1]}}]`,
+			expected: `{"foo":1,"bar":{"n":[2.5]}}`,
+		},
+		{
+			name:  "test large integer",
+			doc:   `{"foo":9007199254740993}`,
+			patch: `[{"op":"test","path":"/foo","value":9007199254740992}]`,
+			err:   "operation 0 (test /foo): test failed",
+		},
+		{
+			name:  "test fails",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"test","path":"/foo","value":"baz"}]`,
+			err:   "operation 0 (test /foo): test failed",
+		},
+		{
+			name:  "remove missing",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/baz"}]`,
+			err:   "operation 1 (remove /baz): member 'baz' not found",
+		},
+		{
+			name:  "replace missing",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":1}]`,
+			err:   "operation 0 (replace /baz): member 'baz' not found",
+		},
+		{
+			name:  "index out of range",
+			doc:   `{"foo":[1]}`,
+			patch: `[{"op":"add","path":"/foo/2","value":1}]`,
+			err:   "operation 0 (add /foo/2): array index 2 out of range",
+		},
+		{
+			name:  "leading zero",
+			doc:   `{"foo":[1,2]}`,
+			patch: `[{"op":"remove","path":"/foo/01"}]`,
+			err:   "operation 0 (remove /foo/01): invalid array index '01'",
+		},
+		{
+			name:  "missing value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/foo"}]`,
+			err:   "operation 0 (add /foo): missing value",
+		},
+		{
+			name:  "move into child",
+			doc:   `{"foo":{"bar":1}}`,
+			patch: `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			err:   "operation 0 (move /foo/bar/baz): cannot move a value into itself",
+		},
+		{
+			name:  "unknown op",
+			doc:   `{}`,
+			patch: `[{"op":"frob","path":"/foo"}]`,
+			err:   "operation 0 (frob /foo): unknown operation 'frob'",
+		},
+		{
+			name:  "invalid pointer",
+			doc:   `{}`,
+			patch: `[{"op":"remove","path":"foo"}]`,
+			err:   "operation 0 (remove foo): invalid JSON pointer 'foo'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(test.patch), &ops); err != nil {
+				t.Fatal(err)
+			}
+			result, err := Apply(mustDecode(t, test.doc), ops)
+			testy.Error(t, test.err, err)
+			if d := diff.JSON([]byte(test.expected), mustEncode(t, result)); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func mustDecode(t *testing.T, src string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func mustEncode(t *testing.T, v interface{}) []byte {
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
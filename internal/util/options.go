@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-kivik/kouch"
 	"github.com/spf13/pflag"
@@ -44,3 +45,8 @@ func setAutoRev(ctx context.Context, o *kouch.Options, flags *pflag.FlagSet) err
 	o.Query().Set("rev", rev)
 	return nil
 }
+
+// Param returns the CouchDB parameter name corresponding to flagName.
+func Param(flagName string) string {
+	return strings.Replace(flagName, "-", "_", -1)
+}
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"context"
+
+	"github.com/go-kivik/kouch"
+	kio "github.com/go-kivik/kouch/io"
+)
+
+// OutputValue writes v to the context's output, formatted by the selected
+// output processor, then closes the output. This is for commands which
+// produce their own output, rather than passing through an HTTP response.
+func OutputValue(ctx context.Context, v interface{}) error {
+	w := kouch.Output(ctx)
+	if isNil(w) {
+		return nil
+	}
+	if err := kio.WriteValue(w, v); err != nil {
+		_ = close(w)
+		return err
+	}
+	return close(w)
+}
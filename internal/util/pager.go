@@ -0,0 +1,128 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+	kio "github.com/go-kivik/kouch/io"
+)
+
+// DefaultPageSize is the number of rows fetched per request when paging
+// through a listing with --all.
+const DefaultPageSize = 1000
+
+// pageRow holds the fields of a view row needed to request the next page.
+type pageRow struct {
+	ID  string          `json:"id"`
+	Key json.RawMessage `json:"key"`
+}
+
+// StreamPages calls next repeatedly, until it reports that there are no more
+// pages. Each returned row is written to the context's underlying output
+// (bypassing any output format) as a single line of JSON, as soon as its page
+// arrives.
+func StreamPages(ctx context.Context, next func() (rows []json.RawMessage, more bool, err error)) error {
+	w := kio.Underlying(kouch.Output(ctx))
+	defer close(kouch.HeadDumper(ctx)) // nolint: errcheck
+	for {
+		rows, more, err := next()
+		if err != nil {
+			return err
+		}
+		if e := WriteRows(w, rows); e != nil {
+			return e
+		}
+		if !more {
+			return close(w)
+		}
+	}
+}
+
+// PageRows walks the entire key range of a view-like resource, such as
+// _all_docs, fetching pageSize rows per request, and streams the rows to the
+// output with StreamPages.
+func PageRows(ctx context.Context, path string, pageSize int, o *kouch.Options) error {
+	if pageSize < 1 {
+		return errors.NewExitError(chttp.ExitFailedToInitialize, "--%s must be positive", kouch.FlagPageSize)
+	}
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	return StreamPages(ctx, Pager(ctx, c, path, pageSize, o))
+}
+
+// Pager returns a function which fetches successive pages of pageSize rows
+// from a view-like resource, suitable for use with StreamPages.
+//
+// The next page is requested with start_key and start_key_doc_id set to the
+// first row beyond the current page, so rows are neither skipped nor repeated,
+// even when keys are not unique. Reduced rows have no document ID, but their
+// keys are unique, so they are paged by key alone.
+func Pager(ctx context.Context, c *chttp.Client, path string, pageSize int, o *kouch.Options) func() ([]json.RawMessage, bool, error) {
+	query := url.Values{}
+	for k, v := range *o.Query() {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(pageSize+1))
+	return func() ([]json.RawMessage, bool, error) {
+		opts := *o.Options
+		opts.Query = query
+		rows, err := fetchPage(ctx, c, path, &opts)
+		if err != nil || len(rows) <= pageSize {
+			return rows, false, err
+		}
+		next := &pageRow{}
+		if e := json.Unmarshal(rows[pageSize], next); e != nil {
+			return nil, false, errors.WrapExitError(chttp.ExitWeirdReply, e)
+		}
+		query.Del("skip")
+		query.Set("start_key", string(next.Key))
+		if next.ID != "" {
+			query.Set("start_key_doc_id", next.ID)
+		}
+		return rows[:pageSize], true, nil
+	}
+}
+
+func fetchPage(ctx context.Context, c *chttp.Client, path string, opts *chttp.Options) ([]json.RawMessage, error) {
+	res, err := c.DoReq(ctx, http.MethodGet, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	var page struct {
+		Rows []json.RawMessage `json:"rows"`
+	}
+	if e := json.NewDecoder(res.Body).Decode(&page); e != nil {
+		return nil, errors.WrapExitError(chttp.ExitWeirdReply, e)
+	}
+	return page.Rows, nil
+}
+
+// WriteRows writes each row to w, as a single line of compact JSON.
+func WriteRows(w io.Writer, rows []json.RawMessage) error {
+	buf := &bytes.Buffer{}
+	for _, row := range rows {
+		buf.Reset()
+		if e := json.Compact(buf, row); e != nil {
+			return errors.WrapExitError(chttp.ExitWeirdReply, e)
+		}
+		buf.WriteByte('\n')
+		if _, e := w.Write(buf.Bytes()); e != nil {
+			return errors.WrapExitError(chttp.ExitWriteError, e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+)
+
+func TestPageRows(t *testing.T) {
+	type prTest struct {
+		pageSize int
+		query    url.Values
+		expected string
+		err      string
+		status   int
+	}
+	// pages serves rows a through e, honoring start_key and limit. With
+	// group=true, the rows are reduced, and so have no id.
+	pages := func(t *testing.T) *httptest.Server {
+		keys := []string{"a", "b", "c", "d", "e"}
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("skip") != "" && q.Get("start_key") != "" {
+				t.Errorf("skip should only be sent with the first page")
+			}
+			reduced := q.Get("group") == "true"
+			if _, ok := q["start_key_doc_id"]; ok && reduced {
+				t.Errorf("start_key_doc_id should not be sent for reduced rows")
+			}
+			var limit, skip int
+			_, _ = fmt.Sscan(q.Get("limit"), &limit)
+			_, _ = fmt.Sscan(q.Get("skip"), &skip)
+			start := 0
+			if sk := q.Get("start_key"); sk != "" {
+				for i, k := range keys {
+					if fmt.Sprintf(`"%s"`, k) == sk {
+						start = i
+					}
+				}
+			}
+			start += skip
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"total_rows":5,"offset":0,"rows":[`)
+			for i := start; i < len(keys) && i < start+limit; i++ {
+				if i > start {
+					_, _ = fmt.Fprint(w, ",\r\n")
+				}
+				if reduced {
+					_, _ = fmt.Fprintf(w, `{"key": "%s", "value": 1}`, keys[i])
+					continue
+				}
+				_, _ = fmt.Fprintf(w, `{"id": "%[1]s", "key": "%[1]s", "value": {"rev": "1-%[1]s"}}`, keys[i])
+			}
+			_, _ = fmt.Fprint(w, "]}")
+		}))
+	}
+	tests := testy.NewTable()
+	tests.Add("invalid page size", prTest{
+		pageSize: 0,
+		err:      "--page-size must be positive",
+		status:   chttp.ExitFailedToInitialize,
+	})
+	tests.Add("multiple pages", prTest{
+		pageSize: 2,
+		expected: `{"id":"a","key":"a","value":{"rev":"1-a"}}
+{"id":"b","key":"b","value":{"rev":"1-b"}}
+{"id":"c","key":"c","value":{"rev":"1-c"}}
+{"id":"d","key":"d","value":{"rev":"1-d"}}
+{"id":"e","key":"e","value":{"rev":"1-e"}}
+`,
+	})
+	tests.Add("exact page multiple", prTest{
+		pageSize: 5,
+		expected: `{"id":"a","key":"a","value":{"rev":"1-a"}}
+{"id":"b","key":"b","value":{"rev":"1-b"}}
+{"id":"c","key":"c","value":{"rev":"1-c"}}
+{"id":"d","key":"d","value":{"rev":"1-d"}}
+{"id":"e","key":"e","value":{"rev":"1-e"}}
+`,
+	})
+	tests.Add("skip", prTest{
+		pageSize: 2,
+		query:    url.Values{"skip": []string{"2"}},
+		expected: `{"id":"c","key":"c","value":{"rev":"1-c"}}
+{"id":"d","key":"d","value":{"rev":"1-d"}}
+{"id":"e","key":"e","value":{"rev":"1-e"}}
+`,
+	})
+	tests.Add("reduced", prTest{
+		pageSize: 2,
+		query:    url.Values{"group": []string{"true"}},
+		expected: `{"key":"a","value":1}
+{"key":"b","value":1}
+{"key":"c","value":1}
+{"key":"d","value":1}
+{"key":"e","value":1}
+`,
+	})
+
+	tests.Run(t, func(t *testing.T, test prTest) {
+		s := pages(t)
+		defer s.Close()
+		buf := &bytes.Buffer{}
+		ctx := kouch.SetOutput(context.Background(), NopWriteCloser(buf))
+		o := kouch.NewOptions()
+		o.Target.Root = s.URL
+		o.Options.Query = test.query
+		err := PageRows(ctx, "/foo/_all_docs", test.pageSize, o)
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if d := diff.Text(test.expected, buf.String()); d != nil {
+			t.Error(d)
+		}
+	})
+}
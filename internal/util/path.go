@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/go-kivik/couchdb/chttp"
 	"github.com/go-kivik/kouch"
@@ -22,3 +23,37 @@ func AttPath(o *kouch.Options) string {
 func DatabasePath(o *kouch.Options) string {
 	return fmt.Sprintf("/%s", url.QueryEscape(o.Database))
 }
+
+// ViewPath calculates the server path to a view.
+func ViewPath(o *kouch.Options) string {
+	return fmt.Sprintf("/%s/%s/_view/%s", url.QueryEscape(o.Database), chttp.EncodeDocID(o.Document), url.QueryEscape(o.View))
+}
+
+// ShowPath calculates the server path to a show function, including the
+// optional document ID.
+func ShowPath(o *kouch.Options) string {
+	return designFunctionPath(o, "_show", chttp.EncodeDocID(o.FunctionDoc))
+}
+
+// ListPath calculates the server path to a list function, applied to a view.
+func ListPath(o *kouch.Options) string {
+	view := strings.Split(o.View, "/")
+	for i, part := range view {
+		view[i] = url.QueryEscape(part)
+	}
+	return designFunctionPath(o, "_list", strings.Join(view, "/"))
+}
+
+// UpdatePath calculates the server path to an update function, including the
+// optional document ID.
+func UpdatePath(o *kouch.Options) string {
+	return designFunctionPath(o, "_update", chttp.EncodeDocID(o.FunctionDoc))
+}
+
+func designFunctionPath(o *kouch.Options, kind, arg string) string {
+	path := fmt.Sprintf("/%s/%s/%s/%s", url.QueryEscape(o.Database), chttp.EncodeDocID(o.Document), kind, url.QueryEscape(o.Function))
+	if arg == "" {
+		return path
+	}
+	return path + "/" + arg
+}
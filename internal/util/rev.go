@@ -12,7 +12,7 @@ import (
 func FetchRev(ctx context.Context, o *kouch.Options) (string, error) {
 	c, err := o.NewClient()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	res, err := c.DoReq(ctx, http.MethodHead, DocPath(o), o.Options)
 	if err != nil {
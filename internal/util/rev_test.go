@@ -0,0 +1,48 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/testy"
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+)
+
+func TestFetchRev(t *testing.T) {
+	type frTest struct {
+		options  *kouch.Options
+		expected string
+		err      string
+		status   int
+	}
+	tests := testy.NewTable()
+	tests.Add("no root", frTest{
+		options: &kouch.Options{Target: &kouch.Target{Database: "foo", Document: "bar"}},
+		err:     "no server root specified",
+		status:  chttp.ExitFailedToInitialize,
+	})
+	tests.Add("success", func(t *testing.T) interface{} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodHead || r.URL.Path != "/foo/bar" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			w.Header().Set("Etag", `"1-xxx"`)
+		}))
+		tests.Cleanup(s.Close)
+		return frTest{
+			options:  &kouch.Options{Target: &kouch.Target{Root: s.URL, Database: "foo", Document: "bar"}, Options: &chttp.Options{}},
+			expected: "1-xxx",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, test frTest) {
+		rev, err := FetchRev(context.Background(), test.options)
+		testy.ExitStatusError(t, test.err, test.status, err)
+		if rev != test.expected {
+			t.Errorf("Unexpected rev: %s", rev)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+	"github.com/go-kivik/kouch/internal/errors"
+)
+
+// ChttpStream performs an HTTP request, as ChttpDo, but decodes the response
+// body as a stream of JSON values, passing each one to fn as soon as it has
+// been received. Writing the output is left to fn.
+func ChttpStream(ctx context.Context, method, path string, o *kouch.Options, fn func(json.RawMessage) error) error {
+	head, body := kouch.HeadDumper(ctx), kouch.Output(ctx)
+	defer close(head) // nolint: errcheck
+	defer close(body) // nolint: errcheck
+	c, err := o.NewClient()
+	if err != nil {
+		return err
+	}
+	res, err := c.DoReq(ctx, method, path, o.Options)
+	if err != nil {
+		return err
+	}
+	if err = chttp.ResponseError(res); err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+
+	if e := writeHead(head, res, !sameFd(head, body)); e != nil {
+		return e
+	}
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var value json.RawMessage
+		if e := dec.Decode(&value); e != nil {
+			if e == io.EOF {
+				return nil
+			}
+			return errors.WrapExitError(chttp.ExitWeirdReply, e)
+		}
+		if e := fn(value); e != nil {
+			return e
+		}
+	}
+}
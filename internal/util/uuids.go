@@ -0,0 +1,37 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kivik/couchdb/chttp"
+	"github.com/go-kivik/kouch"
+)
+
+// UUIDsPath is the server path from which UUIDs are fetched.
+const UUIDsPath = "/_uuids"
+
+// UUIDs fetches count server-generated UUIDs.
+func UUIDs(ctx context.Context, o *kouch.Options, count int) ([]string, error) {
+	c, err := o.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	opts := &chttp.Options{}
+	SetUUIDsCount(opts, count)
+	var res struct {
+		UUIDs []string `json:"uuids"`
+	}
+	_, err = c.DoJSON(ctx, http.MethodGet, UUIDsPath, opts, &res)
+	return res.UUIDs, err
+}
+
+// SetUUIDsCount sets the query to request count UUIDs, rather than the
+// server's default of one.
+func SetUUIDsCount(opts *chttp.Options, count int) {
+	if count != 1 {
+		opts.Query = url.Values{"count": []string{strconv.Itoa(count)}}
+	}
+}
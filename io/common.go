@@ -1,6 +1,7 @@
 package io
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 
@@ -23,6 +24,24 @@ type WrappedWriter interface {
 	Underlying() io.Writer
 }
 
+// ValueWriter is implemented by output processors which can format an
+// already-decoded value synchronously.
+type ValueWriter interface {
+	// WriteValue formats v, and writes it to the underlying writer. It must
+	// not be mixed with calls to Write.
+	WriteValue(v interface{}) error
+}
+
+// WriteValue writes v to w, formatted by w's output processor, if it has one,
+// or as a line of JSON otherwise. Unlike Write, WriteValue does not return
+// until v has been written to the underlying writer.
+func WriteValue(w io.Writer, v interface{}) error {
+	if vw, ok := w.(ValueWriter); ok {
+		return vw.WriteValue(v)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
 type processorFunc func(io.Writer, interface{}) error
 
 // processor implements a basic processor
@@ -37,6 +56,7 @@ type processor struct {
 
 var _ io.WriteCloser = &processor{}
 var _ WrappedWriter = &processor{}
+var _ ValueWriter = &processor{}
 
 func newProcessor(w io.Writer, fn processorFunc) io.WriteCloser {
 	return &processor{
@@ -49,6 +69,20 @@ func (p *processor) Underlying() io.Writer {
 	return p.underlying
 }
 
+// WriteValue round-trips v through JSON, so that it is formatted exactly as
+// if it had been read from a server response.
+func (p *processor) WriteValue(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	unmarshaled, err := unmarshal(json.NewDecoder(bytes.NewReader(buf)))
+	if err != nil {
+		return err
+	}
+	return p.fn(p.underlying, unmarshaled)
+}
+
 func (p *processor) Write(in []byte) (int, error) {
 	if p.w == nil {
 		p.init()
@@ -57,6 +91,9 @@ func (p *processor) Write(in []byte) (int, error) {
 	return n, e
 }
 
+// init starts the goroutine which decodes the input. The input may contain
+// a stream of JSON values, such as a continuous changes feed, in which case
+// each value is passed to fn as soon as it has been read.
 func (p *processor) init() {
 	p.r, p.w = io.Pipe()
 	done := make(chan struct{})
@@ -64,12 +101,21 @@ func (p *processor) init() {
 	go func() {
 		defer func() { close(done) }()
 		defer p.r.Close() // nolint: errcheck
-		unmarshaled, err := unmarshal(p.r)
-		if err != nil {
-			p.err = err
-			return
+		dec := json.NewDecoder(p.r)
+		for first := true; ; first = false {
+			unmarshaled, err := unmarshal(dec)
+			if err == io.EOF && !first {
+				return
+			}
+			if err != nil {
+				p.err = errors.WrapExitError(chttp.ExitWeirdReply, err)
+				return
+			}
+			if err := p.fn(p.underlying, unmarshaled); err != nil {
+				p.err = err
+				return
+			}
 		}
-		p.err = p.fn(p.underlying, unmarshaled)
 	}()
 }
 
@@ -78,13 +124,13 @@ func (p *processor) Close() error {
 		return nil
 	}
 
-	<-p.done
 	_ = p.w.Close() // always returns nil for PipeWriter
+	<-p.done
 	return p.err
 }
 
-func unmarshal(r io.Reader) (interface{}, error) {
+func unmarshal(dec *json.Decoder) (interface{}, error) {
 	var unmarshaled interface{}
-	err := json.NewDecoder(r).Decode(&unmarshaled)
-	return unmarshaled, errors.WrapExitError(chttp.ExitWeirdReply, err)
+	err := dec.Decode(&unmarshaled)
+	return unmarshaled, err
 }
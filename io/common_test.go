@@ -1,6 +1,7 @@
 package io
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"testing"
@@ -38,3 +39,42 @@ var _ io.Reader = &errReader{}
 func (r *errReader) Read(_ []byte) (int, error) {
 	return 0, errors.New("errReader: read error")
 }
+
+func TestWriteValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     outputMode
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "processor",
+			mode:     &yamlMode{},
+			value:    map[string]interface{}{"foo": "bar"},
+			expected: "foo: bar\n",
+		},
+		{
+			name:     "raw",
+			mode:     &rawMode{},
+			value:    map[string]interface{}{"foo": "bar"},
+			expected: `{"foo":"bar"}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			test.mode.config(cmd.PersistentFlags())
+			buf := &bytes.Buffer{}
+			w, err := test.mode.new(cmd.Flags(), buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e := WriteValue(w, test.value); e != nil {
+				t.Fatal(e)
+			}
+			if d := diff.Text(test.expected, buf.String()); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
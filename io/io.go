@@ -212,26 +212,36 @@ func whichInput(cmd *cobra.Command) (flag, value string, err error) {
 	return flag, value, nil
 }
 
-// SelectInput returns an io.ReadCloser for the input.
-func SelectInput(cmd *cobra.Command) (io.ReadCloser, error) {
+// OpenInput returns an io.ReadCloser for the unconverted input, and the name
+// of the data flag which selected it, or "" for stdin. Unlike SelectInput, the
+// full input is returned for --data-json and --data-yaml, which allows
+// commands to read more than one value.
+func OpenInput(cmd *cobra.Command) (flag string, in io.ReadCloser, err error) {
 	flag, data, err := whichInput(cmd)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	if data == "" {
 		// Default to stdin
-		return os.Stdin, nil
+		return "", os.Stdin, nil
 	}
-	var in io.ReadCloser
 	if data[0] == '@' {
 		in, err = os.Open(data[1:])
 		if err != nil {
-			return nil, errors.WrapExitError(chttp.ExitReadError, err)
+			return "", nil, errors.WrapExitError(chttp.ExitReadError, err)
 		}
-	} else {
-		in = ioutil.NopCloser(strings.NewReader(data))
+		return flag, in, nil
+	}
+	return flag, ioutil.NopCloser(strings.NewReader(data)), nil
+}
+
+// SelectInput returns an io.ReadCloser for the input.
+func SelectInput(cmd *cobra.Command) (io.ReadCloser, error) {
+	flag, in, err := OpenInput(cmd)
+	if err != nil {
+		return nil, err
 	}
-	if flag == kouch.FlagData {
+	if flag == "" || flag == kouch.FlagData {
 		return in, nil
 	}
 	defer in.Close() // nolint: errcheck
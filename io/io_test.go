@@ -508,3 +508,25 @@ func TestSelectInput(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenInput(t *testing.T) {
+	cmd := &cobra.Command{}
+	AddFlags(cmd.PersistentFlags())
+	if e := cmd.ParseFlags([]string{"--" + kouch.FlagDataYAML, "_id: a\n---\n_id: b\n"}); e != nil {
+		t.Fatal(e)
+	}
+	flag, f, err := OpenInput(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag != kouch.FlagDataYAML {
+		t.Errorf("Unexpected flag: %s", flag)
+	}
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.Text("_id: a\n---\n_id: b\n", content); d != nil {
+		t.Error(d)
+	}
+}
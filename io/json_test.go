@@ -62,6 +62,11 @@ xx}`,
 			input:    `{"foo": "<>"}`,
 			expected: `{"foo":"\u003c\u003e"}`,
 		},
+		{
+			name:     "stream",
+			input:    "{\"foo\":\"bar\"}\n\n{\"foo\":\"baz\"}\n",
+			expected: "{\"foo\":\"bar\"}\n{\"foo\":\"baz\"}",
+		},
 		{
 			name:  "invalid JSON input",
 			input: "oink",
@@ -20,7 +20,14 @@ var _ outputMode = &yamlMode{}
 func (m *yamlMode) config(_ *pflag.FlagSet) {}
 
 func (m *yamlMode) new(_ *pflag.FlagSet, w io.Writer) (io.WriteCloser, error) {
+	var docs int
 	return newProcessor(w, func(o io.Writer, i interface{}) error {
+		// Separate streamed values into distinct YAML documents
+		if docs++; docs > 1 {
+			if _, err := o.Write([]byte("---\n")); err != nil {
+				return err
+			}
+		}
 		return yaml.NewEncoder(o).Encode(i)
 	}), nil
 }
@@ -44,6 +44,11 @@ qux:
 - 2
 - 3`,
 		},
+		{
+			name:     "stream",
+			input:    "{\"foo\":\"bar\"}\n\n{\"foo\":\"baz\"}\n",
+			expected: "foo: bar\n---\nfoo: baz",
+		},
 		{
 			name:  "invalid JSON input",
 			input: "oink",
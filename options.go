@@ -85,6 +85,82 @@ func (o *Options) SetParamString(f *pflag.FlagSet, flagName string) error {
 	return err
 }
 
+// SetParamJSONString sets the query parameter string value specified by
+// flagName, JSON-encoded, if it differs from the default. This is meant for
+// parameters such as start_key, which CouchDB expects to be valid JSON.
+func (o *Options) SetParamJSONString(f *pflag.FlagSet, flagName string) error {
+	if flag := f.Lookup(flagName); flag == nil {
+		return nil
+	}
+	v, err := f.GetString(flagName)
+	if err == nil && v != f.Lookup(flagName).DefValue {
+		enc, e := json.Marshal(v)
+		if e != nil {
+			return e
+		}
+		o.Query().Add(param(flagName), string(enc))
+	}
+	return err
+}
+
+// SetParamJSON sets the query parameter specified by flagName, if it differs
+// from the default. If the value is valid JSON, it is used as-is. Otherwise it
+// is JSON-encoded as a string. This allows users to pass simple string keys
+// without quoting, while still supporting complex keys.
+func (o *Options) SetParamJSON(f *pflag.FlagSet, flagName string) error {
+	if flag := f.Lookup(flagName); flag == nil {
+		return nil
+	}
+	v, err := f.GetString(flagName)
+	if err == nil && v != f.Lookup(flagName).DefValue {
+		o.Query().Add(param(flagName), string(JSONValue(v)))
+	}
+	return err
+}
+
+// JSONValue returns v as JSON. If v is already valid JSON, it is returned
+// unaltered. Otherwise it is encoded as a JSON string.
+func JSONValue(v string) json.RawMessage {
+	if json.Valid([]byte(v)) {
+		return json.RawMessage(v)
+	}
+	enc, _ := json.Marshal(v)
+	return enc
+}
+
+// JSONString returns v encoded as a JSON string.
+func JSONString(v string) json.RawMessage {
+	enc, _ := json.Marshal(v)
+	return enc
+}
+
+// SetKeys sets the request body to {"keys": [...]}, from the --keys flag, if
+// it was provided. See ParseKeys for the accepted formats.
+func (o *Options) SetKeys(f *pflag.FlagSet, encode func(string) json.RawMessage) error {
+	keys, err := f.GetString(FlagKeys)
+	if err != nil || keys == "" {
+		return err
+	}
+	o.Options.Body = chttp.EncodeBody(map[string][]json.RawMessage{"keys": ParseKeys(keys, encode)})
+	return nil
+}
+
+// ParseKeys parses keys, which may be a JSON array, or a comma-separated list
+// of values, each of which is converted to JSON with encode. Use JSONValue
+// for view keys, and JSONString for document IDs and database names.
+func ParseKeys(keys string, encode func(string) json.RawMessage) []json.RawMessage {
+	var result []json.RawMessage
+	if err := json.Unmarshal([]byte(keys), &result); err == nil {
+		return result
+	}
+	parts := strings.Split(keys, ",")
+	result = make([]json.RawMessage, len(parts))
+	for i, part := range parts {
+		result[i] = encode(strings.TrimSpace(part))
+	}
+	return result
+}
+
 // SetParamInt sets the query parameter string value specified by flagName,
 // if it differs from the default.
 func (o *Options) SetParamInt(f *pflag.FlagSet, flagName string) error {
@@ -0,0 +1,35 @@
+package kouch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flimzy/diff"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		encode   func(string) json.RawMessage
+		expected string
+	}{
+		{name: "json array", input: `["a",1]`, encode: JSONValue, expected: `["a",1]`},
+		{name: "comma-separated", input: "a, 1,[2]", encode: JSONValue, expected: `["a",1,[2]]`},
+		{name: "single", input: "a", encode: JSONValue, expected: `["a"]`},
+		{name: "string array", input: `["a","b"]`, encode: JSONString, expected: `["a","b"]`},
+		{name: "single string", input: "a", encode: JSONString, expected: `["a"]`},
+		{name: "strings", input: "a, 1,[2]", encode: JSONString, expected: `["a","1","[2]"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := json.Marshal(ParseKeys(test.input, test.encode))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(test.expected), result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
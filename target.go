@@ -12,6 +12,7 @@ import (
 )
 
 var errIncompleteURL = errors.NewExitError(chttp.ExitFailedToInitialize, "incomplete target URL")
+var errMissingView = errors.NewExitError(chttp.ExitFailedToInitialize, "invalid target URL: _view must follow the design document")
 
 // TargetScope represents the scope for a target, as relative targets have different
 // meanings in different contexts.
@@ -23,11 +24,10 @@ const (
 	TargetDatabase
 	TargetDocument
 	TargetAttachment
-	// View
-	// Show
-	// List
-	// Update
-	// Rewrite ??
+	TargetView
+	TargetShow
+	TargetList
+	TargetUpdate
 	targetLastScope = iota - 1
 )
 
@@ -44,6 +44,14 @@ func TargetScopeName(scope TargetScope) string {
 		return "document"
 	case TargetAttachment:
 		return "attachment"
+	case TargetView:
+		return "view"
+	case TargetShow:
+		return "show"
+	case TargetList:
+		return "list"
+	case TargetUpdate:
+		return "update"
 	}
 	return ""
 }
@@ -58,6 +66,15 @@ type Target struct {
 	Document string
 	// Filename is the attachment filename.
 	Filename string
+	// View is the view name. The design document is stored in Document.
+	View string
+	// Function is the show, list or update function name. The design
+	// document is stored in Document. For list functions, the view is stored
+	// in View.
+	Function string
+	// FunctionDoc is the optional document ID passed to a show or update
+	// function.
+	FunctionDoc string
 	// User is the Auth username
 	User string
 	// Password is the Auth password
@@ -129,6 +146,14 @@ func ParseTarget(scope TargetScope, src string) (*Target, error) {
 		return document(target, src)
 	case TargetAttachment:
 		return attachment(target, src)
+	case TargetView:
+		return view(target, src)
+	case TargetShow:
+		return designFunction(target, src, "_show")
+	case TargetList:
+		return designFunction(target, src, "_list")
+	case TargetUpdate:
+		return designFunction(target, src, "_update")
 	}
 	return nil, errors.New("invalid scope")
 }
@@ -162,6 +187,75 @@ func attachment(t *Target, src string) (*Target, error) {
 	return document(t, src)
 }
 
+func view(t *Target, src string) (*Target, error) {
+	src, t.View = lastSegment(src)
+	if t.View == "" {
+		return nil, errIncompleteURL
+	}
+	if ddoc := strings.TrimSuffix(src, "/_view"); ddoc != src {
+		return designDoc(t, ddoc)
+	}
+	// Without _view, only the short form, which omits _design/, is valid.
+	if _, ddoc := chopDocument(src); strings.HasPrefix(ddoc, "_design/") {
+		return nil, errMissingView
+	}
+	return designDoc(t, src)
+}
+
+// designDoc chops the design document off the right end of src, adding the
+// _design/ prefix if it was omitted, then parses the remainder as a database.
+func designDoc(t *Target, src string) (*Target, error) {
+	src, t.Document = chopDocument(src)
+	if t.Document == "" || t.Document[0] == '_' && !strings.HasPrefix(t.Document, "_design/") {
+		return nil, errIncompleteURL
+	}
+	if !strings.HasPrefix(t.Document, "_design/") {
+		t.Document = "_design/" + t.Document
+	}
+	return database(t, src)
+}
+
+// designFunction parses a show, list or update function target, where kind
+// is one of _show, _list or _update. The full form is
+// {db}/_design/{ddoc}/{kind}/{func}/{arg}, where {arg} is the optional
+// document ID for show and update functions, or the view name for list
+// functions. The short form omits _design/ and {kind}, and does not accept a
+// document ID.
+func designFunction(t *Target, src, kind string) (*Target, error) {
+	parts := strings.Split(src, "/")
+	i := len(parts) - 1
+	for ; i >= 0 && parts[i] != kind; i-- {
+	}
+	if i < 0 {
+		if kind == "_list" {
+			src, t.View = lastSegment(src)
+			if t.View == "" {
+				return nil, errIncompleteURL
+			}
+		}
+		src, t.Function = lastSegment(src)
+		if t.Function == "" {
+			return nil, errIncompleteURL
+		}
+		return designDoc(t, src)
+	}
+	args := parts[i+1:]
+	if len(args) == 0 || args[0] == "" {
+		return nil, errIncompleteURL
+	}
+	t.Function = args[0]
+	arg := strings.Join(args[1:], "/")
+	if kind == "_list" {
+		if arg == "" {
+			return nil, errIncompleteURL
+		}
+		t.View = arg
+	} else {
+		t.FunctionDoc = arg
+	}
+	return designDoc(t, strings.Join(parts[:i], "/"))
+}
+
 func lastSegment(src string) (string, string) {
 	parts := strings.Split(src, "/")
 	l := len(parts)
@@ -169,7 +263,9 @@ func lastSegment(src string) (string, string) {
 }
 
 func validate(t *Target) error {
-	parts := []string{t.Root, t.Database, t.Document, t.Filename}
+	// A filename is never set along with a view or function
+	leaf := t.Filename + t.View + t.Function
+	parts := []string{t.Root, t.Database, t.Document, leaf}
 	test := strings.Trim(strings.Join(parts, "\t"), "\t")
 	if strings.Contains(test, "\t\t") {
 		// This means one of the inner elements is empty
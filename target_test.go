@@ -425,6 +425,147 @@ func TestParseTarget(t *testing.T) {
 			src:      "@1:2.txt",
 			expected: &Target{Filename: "@1:2.txt"},
 		},
+		{
+			scope:  TargetView,
+			name:   "view name only",
+			src:    "baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetView,
+			name:     "short form",
+			src:      "bar/baz",
+			expected: &Target{Document: "_design/bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "short form with db",
+			src:      "foo/bar/baz",
+			expected: &Target{Database: "foo", Document: "_design/bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "relative",
+			src:      "_design/bar/_view/baz",
+			expected: &Target{Document: "_design/bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "db, design doc, view",
+			src:      "foo/_design/bar/_view/baz",
+			expected: &Target{Database: "foo", Document: "_design/bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "full url",
+			src:      "http://localhost:5984/foo/_design/bar/_view/baz",
+			expected: &Target{Root: "http://localhost:5984", Database: "foo", Document: "_design/bar", View: "baz"},
+		},
+		{
+			scope:    TargetView,
+			name:     "design doc without prefix",
+			src:      "foo/_view/baz",
+			expected: &Target{Document: "_design/foo", View: "baz"},
+		},
+		{
+			scope:  TargetView,
+			name:   "missing design doc",
+			src:    "_view/baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:  TargetView,
+			name:   "design prefix without _view",
+			src:    "db/_design/ddoc/name",
+			err:    "invalid target URL: _view must follow the design document",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:  TargetView,
+			name:   "relative without _view",
+			src:    "_design/ddoc/name",
+			err:    "invalid target URL: _view must follow the design document",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:  TargetView,
+			name:   "design prefix without name",
+			src:    "db/_design/foo",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetShow,
+			name:     "show short form",
+			src:      "foo/bar/baz",
+			expected: &Target{Database: "foo", Document: "_design/bar", Function: "baz"},
+		},
+		{
+			scope:    TargetShow,
+			name:     "show with doc",
+			src:      "foo/_design/bar/_show/baz/qux",
+			expected: &Target{Database: "foo", Document: "_design/bar", Function: "baz", FunctionDoc: "qux"},
+		},
+		{
+			scope:    TargetShow,
+			name:     "show with design doc",
+			src:      "http://host.com/foo/_design/bar/_show/baz/_design/qux",
+			expected: &Target{Root: "http://host.com", Database: "foo", Document: "_design/bar", Function: "baz", FunctionDoc: "_design/qux"},
+		},
+		{
+			scope:  TargetShow,
+			name:   "show missing function",
+			src:    "foo/_design/bar/_show",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:  TargetShow,
+			name:   "show missing design doc",
+			src:    "baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetList,
+			name:     "list short form",
+			src:      "foo/bar/baz/qux",
+			expected: &Target{Database: "foo", Document: "_design/bar", Function: "baz", View: "qux"},
+		},
+		{
+			scope:    TargetList,
+			name:     "list other ddoc",
+			src:      "http://host.com/foo/_design/bar/_list/baz/other/qux",
+			expected: &Target{Root: "http://host.com", Database: "foo", Document: "_design/bar", Function: "baz", View: "other/qux"},
+		},
+		{
+			scope:  TargetList,
+			name:   "list missing view",
+			src:    "foo/_design/bar/_list/baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
+		{
+			scope:    TargetUpdate,
+			name:     "update without doc",
+			src:      "foo/_design/bar/_update/baz",
+			expected: &Target{Database: "foo", Document: "_design/bar", Function: "baz"},
+		},
+		{
+			scope:    TargetUpdate,
+			name:     "update with doc",
+			src:      "http://host.com/couchdb/foo/_design/bar/_update/baz/qux",
+			expected: &Target{Root: "http://host.com/couchdb", Database: "foo", Document: "_design/bar", Function: "baz", FunctionDoc: "qux"},
+		},
+		{
+			scope:  TargetUpdate,
+			name:   "update local doc as ddoc",
+			src:    "foo/_local/bar/_update/baz",
+			err:    "incomplete target URL",
+			status: chttp.ExitFailedToInitialize,
+		},
 	}
 	for _, test := range tests {
 		scopeName := TargetScopeName(test.scope)